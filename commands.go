@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+	"golang.org/x/exp/slices"
+)
+
+// command is a subcommand acting on snippets, given the arguments following
+// its name.
+type command func(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error
+
+// commands are the subcommands to manage snippets from scripts.
+var commands = map[string]command{
+	"add":    runAdd,
+	"edit":   runEdit,
+	"rm":     runRm,
+	"mv":     runMv,
+	"cp":     runCp,
+	"show":   runShow,
+	"export": runExport,
+	"stats":  runStats,
+}
+
+// usageError reports invalid flags, missing or extra arguments, which exit
+// with exitUsage.
+type usageError string
+
+// Error returns the message of the usage error.
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// errUsage returns the error reported for missing or extra arguments.
+func errUsage(usage string) error {
+	return usageError("usage: nap " + usage)
+}
+
+// destination returns the snippet moved or copied to dest, which is either a
+// folder ending with a slash, a name with an optional language, or both.
+func destination(snippet store.Snippet, dest string) store.Snippet {
+	to := snippet
+	if i := strings.LastIndex(dest, "/"); i >= 0 {
+		to.Folder = strings.Trim(dest[:i], "/")
+		dest = dest[i+1:]
+	}
+	if dest != "" {
+		to.Name = dest
+		if ext := filepath.Ext(dest); ext != "" {
+			to.Name, to.Language = strings.TrimSuffix(dest, ext), ext[1:]
+		}
+		to.File = store.FileName(to.Name, to.Language)
+	}
+	return to
+}
+
+// runAdd adds a snippet with the content of a file, named after the file
+// unless a name is given. A name ending with a slash is the folder.
+func runAdd(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var filter snippetFilter
+	var force bool
+	flags := newFlagSet("add")
+	filter.register(flags)
+	flags.BoolVar(&force, "force", false, "replace an existing snippet")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage("add <file> [folder/name.language]")
+	}
+
+	content, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(args[0])
+	if len(args) > 1 {
+		name = args[1]
+		if strings.HasSuffix(name, "/") {
+			name += filepath.Base(args[0])
+		}
+	}
+	folder, name, language := parseName(name)
+	snippet := store.Snippet{
+		Folder:   folder,
+		Name:     name,
+		File:     store.FileName(name, language),
+		Language: language,
+		Tags:     filter.tags,
+		Favorite: filter.favorites,
+	}
+	if snippet.Tags == nil {
+		snippet.Tags = []string{}
+	}
+	if _, err := st.Get(snippet.Path()); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to replace it", snippet)
+	}
+	if _, err := st.Overwrite(snippet, content); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Added %s\n", snippet)
+	return nil
+}
+
+// runEdit opens the snippet in the editor, at the given line if any, and
+// records the changes made to it.
+func runEdit(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var lookup snippetLookup
+	var line int
+	flags := newFlagSet("edit")
+	lookup.register(flags)
+	flags.IntVar(&line, "line", 0, "line to open the snippet at")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage("edit <snippet>[:line]")
+	}
+
+	search, at := splitLine(args[0])
+	if line <= 0 {
+		line = at
+	}
+	snippet, err := lookup.resolve(search, snippets)
+	if err != nil {
+		return err
+	}
+	before, _ := st.Content(snippet)
+	cmd := editorCmd(config, st.FilePath(snippet), line)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not edit %s: %w", snippet, err)
+	}
+	if after, _ := st.Content(snippet); bytes.Equal(before, after) {
+		return nil
+	}
+	_, err = st.Edited(snippet)
+	return err
+}
+
+// runRm moves the snippets to the trash.
+func runRm(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var lookup snippetLookup
+	flags := newFlagSet("rm")
+	lookup.register(flags)
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errUsage("rm <snippet>...")
+	}
+
+	for _, search := range args {
+		snippet, err := lookup.resolve(search, snippets)
+		if err != nil {
+			return err
+		}
+		if _, err := st.Trash(snippet); err != nil {
+			return fmt.Errorf("could not delete %s: %w", snippet, err)
+		}
+		fmt.Fprintf(w, "Moved %s to the trash\n", snippet)
+	}
+	return nil
+}
+
+// runMv renames the snippet or moves it to another folder.
+func runMv(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var lookup snippetLookup
+	var force bool
+	flags := newFlagSet("mv")
+	lookup.register(flags)
+	flags.BoolVar(&force, "force", false, "move an existing snippet at the destination to the trash")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errUsage("mv <snippet> <folder/name.language>")
+	}
+
+	snippet, err := lookup.resolve(args[0], snippets)
+	if err != nil {
+		return err
+	}
+	to := destination(snippet, args[1])
+	if force {
+		_, err = st.Replace(snippet, to)
+	} else {
+		err = st.Move(snippet, to)
+	}
+	if errors.Is(err, store.ErrExists) {
+		return fmt.Errorf("%s already exists, use --force to replace it", to)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Moved %s to %s\n", snippet, to)
+	return nil
+}
+
+// runCp copies the snippet, with its content and metadata, to a new snippet.
+func runCp(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var lookup snippetLookup
+	var force bool
+	flags := newFlagSet("cp")
+	lookup.register(flags)
+	flags.BoolVar(&force, "force", false, "replace an existing snippet at the destination")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errUsage("cp <snippet> <folder/name.language>")
+	}
+
+	snippet, err := lookup.resolve(args[0], snippets)
+	if err != nil {
+		return err
+	}
+	content, err := st.Content(snippet)
+	if err != nil {
+		return err
+	}
+	to := destination(snippet, args[1])
+	to.ID = ""
+	to.Created, to.Modified, to.LastUsed, to.Uses = time.Time{}, time.Time{}, time.Time{}, 0
+	to.Tags = slices.Clone(snippet.Tags)
+	if existing, err := st.Get(to.Path()); err == nil && (!force || existing.Same(snippet)) {
+		return fmt.Errorf("%s already exists, use --force to replace it", to)
+	}
+	if _, err := st.Overwrite(to, content); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Copied %s to %s\n", snippet, to)
+	return nil
+}
+
+// runShow prints the content of the snippet, or its metadata in a readable
+// or machine-readable format.
+func runShow(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var lookup snippetLookup
+	var meta, raw bool
+	var options outputOptions
+	vars := varsFlag{}
+	flags := newFlagSet("show")
+	options.register(flags, false)
+	lookup.register(flags)
+	flags.BoolVar(&meta, "meta", false, "print the metadata instead of the content")
+	flags.BoolVar(&raw, "raw", false, "print templates without filling in placeholders")
+	flags.Var(vars, "var", "value of a template placeholder as name=value")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errUsage("show <snippet>")
+	}
+
+	snippet, err := lookup.resolve(args[0], snippets)
+	if err != nil {
+		return err
+	}
+	if options.format != "" {
+		write, err := formatter(options.format)
+		if err != nil {
+			return err
+		}
+		return write(w, snippetInfo{snippet, st.FilePath(snippet)})
+	}
+	if !meta {
+		return printSnippet(w, snippet, st, config, vars, raw)
+	}
+	fmt.Fprintf(w, "id:       %s\n", snippet.ID)
+	fmt.Fprintf(w, "name:     %s\n", snippet.Name)
+	fmt.Fprintf(w, "folder:   %s\n", snippet.Folder)
+	fmt.Fprintf(w, "language: %s\n", snippet.Language)
+	fmt.Fprintf(w, "tags:     %s\n", tagsString(snippet.Tags))
+	fmt.Fprintf(w, "favorite: %t\n", snippet.Favorite)
+	fmt.Fprintf(w, "created:  %s\n", snippet.Created.Format(time.RFC3339))
+	fmt.Fprintf(w, "modified: %s\n", snippet.Modified.Format(time.RFC3339))
+	fmt.Fprintf(w, "used:     %s\n", formatUsed(snippet.LastUsed, time.RFC3339))
+	fmt.Fprintf(w, "uses:     %d\n", snippet.Uses)
+	fmt.Fprintf(w, "path:     %s\n", st.FilePath(snippet))
+	return nil
+}
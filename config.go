@@ -24,6 +24,25 @@ type Config struct {
 
 	Theme string `env:"NAP_THEME" yaml:"theme"`
 
+	// Editor is the command editing snippets, before $VISUAL and $EDITOR.
+	// Editors are the profiles of editors by command name, to open snippets
+	// at a line and wait for graphical editors, see editorProfiles.
+	Editor  string                   `env:"NAP_EDITOR" yaml:"editor"`
+	Editors map[string]EditorProfile `yaml:"editors"`
+
+	// BuiltinEditor edits snippets in the content pane instead of the editor,
+	// which is also done when the editor is not installed.
+	BuiltinEditor bool `env:"NAP_BUILTIN_EDITOR" yaml:"builtin_editor"`
+
+	// TrashDays is the number of days after which deleted snippets are purged
+	// from the trash. Zero keeps them until the trash is emptied.
+	TrashDays int `env:"NAP_TRASH_DAYS" yaml:"trash_days"`
+
+	// Git commits every change to the snippets in the home folder, which is
+	// synchronized with GitRemote, a remote name or URL, by nap sync.
+	Git       bool   `env:"NAP_GIT" yaml:"git"`
+	GitRemote string `env:"NAP_GIT_REMOTE" yaml:"git_remote"`
+
 	PrimaryColor        string `env:"NAP_PRIMARY_COLOR" yaml:"primary_color"`
 	PrimaryColorSubdued string `env:"NAP_PRIMARY_COLOR_SUBDUED" yaml:"primary_color_subdued"`
 	BrightGreenColor    string `env:"NAP_BRIGHT_GREEN" yaml:"bright_green"`
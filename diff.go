@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is the operation turning one version of a line into another.
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+// diffLine is a line of a diff between two versions of a snippet.
+type diffLine struct {
+	Op   diffOp
+	Text string
+}
+
+// maxDiffCells bounds the size of the table used to compute the longest
+// common subsequence of two versions. Larger versions are diffed as if every
+// line changed.
+const maxDiffCells = 4_000_000
+
+// diffLines returns the lines of a line by line diff from a to b.
+func diffLines(a, b string) []diffLine {
+	x, y := splitLines(a), splitLines(b)
+	n, m := len(x), len(y)
+
+	var lines []diffLine
+	if n*m > maxDiffCells {
+		for _, line := range x {
+			lines = append(lines, diffLine{diffDelete, line})
+		}
+		for _, line := range y {
+			lines = append(lines, diffLine{diffInsert, line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and
+	// y[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, diffLine{diffEqual, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{diffDelete, x[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{diffInsert, y[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, diffLine{diffDelete, x[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, diffLine{diffInsert, y[j]})
+	}
+	return lines
+}
+
+// unifiedDiff formats the diff in the unified format, showing each change
+// with the given number of lines of context in hunks starting with a
+// @@ -start,count +start,count @@ header. Unchanged versions have no hunks.
+func unifiedDiff(lines []diffLine, context int) []string {
+	// shown marks the lines that are changed or close enough to a change.
+	shown := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == diffEqual {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(lines) {
+				shown[j] = true
+			}
+		}
+	}
+
+	var out []string
+	var aLine, bLine int
+	for i := 0; i < len(lines); {
+		if !shown[i] {
+			aLine, bLine = advance(lines[i], aLine, bLine)
+			i++
+			continue
+		}
+
+		aStart, bStart := aLine, bLine
+		var hunk []string
+		for ; i < len(lines) && shown[i]; i++ {
+			aLine, bLine = advance(lines[i], aLine, bLine)
+			hunk = append(hunk, diffPrefix(lines[i].Op)+lines[i].Text)
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aStart, aLine-aStart), hunkRange(bStart, bLine-bStart)))
+		out = append(out, hunk...)
+	}
+	return out
+}
+
+// advance returns the line counts of both versions after the diff line.
+func advance(line diffLine, a, b int) (int, int) {
+	switch line.Op {
+	case diffDelete:
+		return a + 1, b
+	case diffInsert:
+		return a, b + 1
+	default:
+		return a + 1, b + 1
+	}
+}
+
+// hunkRange formats the start and count of the lines of a hunk in one
+// version, with lines numbered from 1.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// diffPrefix returns the prefix of a line of a unified diff.
+func diffPrefix(op diffOp) string {
+	switch op {
+	case diffDelete:
+		return "-"
+	case diffInsert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// splitLines splits content into lines, ignoring the final newline.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tt := []struct {
+		Name string
+		A    string
+		B    string
+		Want string
+	}{
+		{Name: "unchanged", A: "a\nb\n", B: "a\nb\n", Want: ""},
+		{Name: "created", A: "", B: "a\nb\n", Want: "@@ -0,0 +1,2 @@\n+a\n+b"},
+		{Name: "changed", A: "a\nb\nc\nd\ne\nf\ng\n", B: "a\nb\nc\nD\ne\nf\ng\n", Want: "@@ -2,5 +2,5 @@\n b\n c\n-d\n+D\n e\n f"},
+		{Name: "hunks", A: "1\n2\n3\n4\n5\n6\n7\n8\n", B: "0\n1\n2\n3\n4\n5\n6\n7\n", Want: "@@ -1,2 +1,3 @@\n+0\n 1\n 2\n@@ -6,3 +7,2 @@\n 6\n 7\n-8"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := strings.Join(unifiedDiff(diffLines(tc.A, tc.B), 2), "\n")
+			if got != tc.Want {
+				t.Logf("diff is incorrect: want %q but got %q", tc.Want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/maaslalani/nap/store"
+)
+
+// tabRune stands in for tabs in the textarea, which would otherwise replace
+// them with spaces. It is in the private use area so that it is never typed.
+const tabRune = '\uE000'
+
+// maxUndo is the number of edits which can be undone.
+const maxUndo = 500
+
+// editorWidth is the width of the textarea, wide enough for lines to never be
+// wrapped: the editor scrolls horizontally instead.
+const editorWidth = 1 << 16
+
+// editKind is the kind of an edit, so that successive edits of the same kind,
+// such as typing a word, are undone at once.
+type editKind int
+
+const (
+	noEdit editKind = iota
+	wordEdit
+	spaceEdit
+	deleteEdit
+	otherEdit
+)
+
+// editorSnapshot is the content of the editor and the position of the cursor,
+// to undo and redo edits.
+type editorSnapshot struct {
+	value    string
+	row, col int
+}
+
+// contentEditor edits the content of a snippet in the content pane. It is
+// built on a textarea, which handles the input, but renders the content itself
+// to keep the syntax highlighting.
+type contentEditor struct {
+	textarea textarea.Model
+	snippet  store.Snippet
+	// saved is the content as last saved, to tell whether it was modified,
+	// and edited whether it was saved at all, which is a use of the snippet.
+	saved  string
+	edited bool
+	// crlf is whether the lines of the file end with \r\n, which the textarea
+	// does not support.
+	crlf       bool
+	undo, redo []editorSnapshot
+	lastEdit   editKind
+	// the first line and column shown.
+	top, left int
+	// err is the error of the last save, if any.
+	err error
+}
+
+// newContentEditor returns an editor with the content of the snippet and the
+// cursor at its start.
+func newContentEditor(snippet store.Snippet, content string) contentEditor {
+	ta := textarea.New()
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.MaxWidth = 0
+	ta.ShowLineNumbers = false
+	ta.Prompt = ""
+	ta.SetWidth(editorWidth)
+	ta.Cursor.SetMode(cursor.CursorHide)
+	ta.Focus()
+
+	crlf := strings.Contains(content, "\r\n")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\t", string(tabRune))
+	e := contentEditor{textarea: ta, snippet: snippet, crlf: crlf}
+	e.restore(editorSnapshot{value: content})
+	e.saved = e.content()
+	return e
+}
+
+// content returns the content being edited, as it is saved.
+func (e *contentEditor) content() string {
+	content := strings.ReplaceAll(e.textarea.Value(), string(tabRune), "\t")
+	if e.crlf {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	return content
+}
+
+// modified returns whether the content changed since it was last saved.
+func (e *contentEditor) modified() bool {
+	return e.content() != e.saved
+}
+
+// snapshot returns the content and the position of the cursor.
+func (e *contentEditor) snapshot() editorSnapshot {
+	info := e.textarea.LineInfo()
+	return editorSnapshot{
+		value: e.textarea.Value(),
+		row:   e.textarea.Line(),
+		col:   info.StartColumn + info.ColumnOffset,
+	}
+}
+
+// restore replaces the content and moves the cursor as in the snapshot.
+func (e *contentEditor) restore(s editorSnapshot) {
+	e.textarea.SetValue(s.value)
+	for e.textarea.Line() > s.row {
+		e.textarea.CursorUp()
+	}
+	e.textarea.SetCursor(s.col)
+}
+
+// update edits the content with the key press, or the text pasted, and
+// records the edit to undo it.
+func (e *contentEditor) update(msg tea.Msg) tea.Cmd {
+	edit := otherEdit
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		edit = editKindOf(keyMsg)
+		if keyMsg.Type == tea.KeyTab {
+			keyMsg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{tabRune}}
+		} else if keyMsg.Type == tea.KeyRunes {
+			keyMsg.Runes = []rune(strings.NewReplacer("\t", string(tabRune), "\r", "").Replace(string(keyMsg.Runes)))
+		}
+		msg = keyMsg
+	}
+	before := e.snapshot()
+	var cmd tea.Cmd
+	e.textarea, cmd = e.textarea.Update(msg)
+	e.record(before, edit)
+	return cmd
+}
+
+// record adds the snapshot taken before an edit to the edits to undo, unless
+// the edit continues the previous one. Moving the cursor ends an edit.
+func (e *contentEditor) record(before editorSnapshot, edit editKind) {
+	if e.textarea.Value() == before.value {
+		e.lastEdit = noEdit
+		return
+	}
+	if edit == otherEdit || edit != e.lastEdit {
+		e.undo = pushSnapshot(e.undo, before)
+	}
+	e.lastEdit = edit
+	e.redo = nil
+}
+
+// editKindOf returns the kind of edit a key press makes, if any.
+func editKindOf(msg tea.KeyMsg) editKind {
+	switch msg.Type {
+	case tea.KeySpace:
+		return spaceEdit
+	case tea.KeyBackspace, tea.KeyDelete:
+		return deleteEdit
+	case tea.KeyRunes:
+		if len(msg.Runes) != 1 {
+			return otherEdit
+		}
+		if unicode.IsSpace(msg.Runes[0]) {
+			return spaceEdit
+		}
+		return wordEdit
+	}
+	return otherEdit
+}
+
+// pushSnapshot appends the snapshot, dropping the oldest one past maxUndo.
+func pushSnapshot(snapshots []editorSnapshot, s editorSnapshot) []editorSnapshot {
+	snapshots = append(snapshots, s)
+	if len(snapshots) > maxUndo {
+		snapshots = snapshots[len(snapshots)-maxUndo:]
+	}
+	return snapshots
+}
+
+// undoEdit reverts the last edit.
+func (e *contentEditor) undoEdit() {
+	if len(e.undo) == 0 {
+		return
+	}
+	e.redo = pushSnapshot(e.redo, e.snapshot())
+	e.restore(e.undo[len(e.undo)-1])
+	e.undo = e.undo[:len(e.undo)-1]
+	e.lastEdit = noEdit
+}
+
+// redoEdit makes the last edit undone again.
+func (e *contentEditor) redoEdit() {
+	if len(e.redo) == 0 {
+		return
+	}
+	e.undo = pushSnapshot(e.undo, e.snapshot())
+	e.restore(e.redo[len(e.redo)-1])
+	e.redo = e.redo[:len(e.redo)-1]
+	e.lastEdit = noEdit
+}
+
+// view returns the syntax highlighted lines in view and their numbers,
+// scrolling to keep the cursor in view.
+func (e *contentEditor) view(width, height int, theme string) (code, lineNumbers string) {
+	value := strings.ReplaceAll(e.textarea.Value(), string(tabRune), "\t")
+	lines := strings.Split(value, "\n")
+	highlighted := lines
+	var b bytes.Buffer
+	if err := quick.Highlight(&b, value, e.snippet.Language, "terminal16m", theme); err == nil {
+		if h := strings.Split(b.String(), "\n"); len(h) == len(lines) {
+			highlighted = h
+		}
+	}
+
+	cursor := e.snapshot()
+	column := displayWidth([]rune(lines[cursor.row])[:cursor.col])
+	e.top = scrollTo(e.top, cursor.row, height)
+	e.left = scrollTo(e.left, column, width-1)
+
+	var c, n strings.Builder
+	for i := e.top; i < len(lines) && i < e.top+height; i++ {
+		col := -1
+		if i == cursor.row {
+			col = cursor.col
+		}
+		c.WriteString(renderLine(highlighted[i], col, e.left, width) + "\n")
+		n.WriteString(fmt.Sprintf("%3d \n", i+1))
+	}
+	if e.top+height > len(lines) {
+		n.WriteString("  ~ \n")
+	}
+	return c.String(), n.String()
+}
+
+// scrollTo returns the offset keeping the position within the size.
+func scrollTo(offset, position, size int) int {
+	if position < offset {
+		return position
+	}
+	if size > 0 && position >= offset+size {
+		return position - size + 1
+	}
+	return offset
+}
+
+// displayWidth returns the number of columns the runes take, with tabs
+// expanded to tabSpaces.
+func displayWidth(runes []rune) int {
+	var width int
+	for _, r := range runes {
+		if r == '\t' {
+			width += tabSpaces
+		} else {
+			width += lipgloss.Width(string(r))
+		}
+	}
+	return width
+}
+
+// renderLine returns the columns of the highlighted line from left to
+// left+width, with tabs expanded and the rune at the cursor index, or the end
+// of the line, in reverse video. A negative cursor shows no cursor.
+func renderLine(line string, cursor, left, width int) string {
+	const reverse, noReverse = "\x1b[7m", "\x1b[27m"
+
+	var b strings.Builder
+	var column, index int
+	for line != "" {
+		if strings.HasPrefix(line, "\x1b[") {
+			if end := strings.IndexFunc(line[2:], func(r rune) bool { return r >= '@' && r <= '~' }); end >= 0 {
+				b.WriteString(line[:end+3])
+				line = line[end+3:]
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+		s := string(r)
+		if r == '\t' {
+			s = strings.Repeat(" ", tabSpaces)
+		}
+		w := lipgloss.Width(s)
+		if column >= left && column+w <= left+width {
+			if index == cursor {
+				s = reverse + s + noReverse
+			}
+			b.WriteString(s)
+		}
+		column += w
+		index++
+	}
+	if cursor >= index && column >= left && column < left+width {
+		b.WriteString(reverse + " " + noReverse)
+	}
+	return b.String() + "\x1b[0m"
+}
+
+// openEditor edits the content of the selected snippet in the content pane.
+func (m *Model) openEditor() tea.Cmd {
+	snippet := m.selectedSnippet()
+	content, err := m.store.Content(snippet)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		m.displayError("Unable to read snippet.")
+		return nil
+	}
+	m.editor = newContentEditor(snippet, string(content))
+	m.pane = contentPane
+	return changeState(editingContentState)
+}
+
+// updateEditor handles the key presses while editing the content.
+func (m *Model) updateEditor(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.editorKeys.Save):
+		m.saveEditor()
+		return nil
+	case key.Matches(msg, m.editorKeys.Undo):
+		m.editor.undoEdit()
+		return nil
+	case key.Matches(msg, m.editorKeys.Redo):
+		m.editor.redoEdit()
+		return nil
+	case key.Matches(msg, m.editorKeys.Close):
+		if m.editor.modified() {
+			return changeState(savingContentState)
+		}
+		return m.closeEditor()
+	}
+	return m.editor.update(msg)
+}
+
+// updateSaving handles the key presses while asking whether to save the
+// changes before closing the editor.
+func (m *Model) updateSaving(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y":
+		if !m.saveEditor() {
+			return changeState(editingContentState)
+		}
+		return m.closeEditor()
+	case "n":
+		return m.closeEditor()
+	}
+	return changeState(editingContentState)
+}
+
+// saveEditor writes the content being edited to the snippet file and returns
+// whether it succeeded.
+func (m *Model) saveEditor() bool {
+	content := m.editor.content()
+	m.editor.err = m.store.Write(m.editor.snippet, []byte(content))
+	if m.editor.err != nil {
+		return false
+	}
+	m.editor.saved, m.editor.edited = content, true
+	if snippet, err := m.store.Get(m.editor.snippet.Path()); err == nil {
+		m.editor.snippet = snippet
+		m.setSelectedSnippet(snippet)
+	}
+	return true
+}
+
+// closeEditor leaves the editor and shows the content of the snippet,
+// recording a use of the snippet if it was edited.
+func (m *Model) closeEditor() tea.Cmd {
+	m.pane = snippetPane
+	cmd := tea.Batch(changeState(navigatingState), m.updateContent())
+	if m.editor.edited {
+		cmd = tea.Batch(cmd, m.useSnippet(m.editor.snippet))
+	}
+	return cmd
+}
+
+// editorTitle returns the title shown while editing the content.
+func (m *Model) editorTitle() string {
+	switch {
+	case m.state == savingContentState:
+		return m.ListStyle.DeletedTitleBar.Render("Save Changes? (y/n/esc)")
+	case m.editor.err != nil:
+		return m.ListStyle.DeletedTitleBar.Render("Unable to Save!")
+	case m.editor.modified():
+		return m.ListStyle.TitleBar.Render("Editing • Modified")
+	}
+	return m.ListStyle.TitleBar.Render("Editing")
+}
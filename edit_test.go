@@ -0,0 +1,203 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maaslalani/nap/store"
+)
+
+func TestContentEditor(t *testing.T) {
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	typed := func(s string) []tea.Msg {
+		var msgs []tea.Msg
+		for _, r := range s {
+			if r == ' ' {
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{r}})
+			} else {
+				msgs = append(msgs, runes(string(r)))
+			}
+		}
+		return msgs
+	}
+	concat := func(msgs ...[]tea.Msg) []tea.Msg {
+		var all []tea.Msg
+		for _, m := range msgs {
+			all = append(all, m...)
+		}
+		return all
+	}
+	backspace := tea.KeyMsg{Type: tea.KeyBackspace}
+	end := tea.KeyMsg{Type: tea.KeyEnd}
+
+	tt := []struct {
+		name     string
+		content  string
+		msgs     []tea.Msg
+		undo     int
+		redo     int
+		want     string
+		modified bool
+	}{
+		{
+			name:    "unchanged",
+			content: "func main() {\n\tprintln()\n}\n",
+			want:    "func main() {\n\tprintln()\n}\n",
+		},
+		{
+			name:     "crlf",
+			content:  "a\r\nb\r\n",
+			msgs:     concat(typed("x"), []tea.Msg{tea.KeyMsg{Type: tea.KeyEnter}}),
+			want:     "x\r\na\r\nb\r\n",
+			modified: true,
+		},
+		{
+			name:     "tab",
+			content:  "b",
+			msgs:     []tea.Msg{tea.KeyMsg{Type: tea.KeyTab}, runes("a\tc")},
+			want:     "\ta\tcb",
+			modified: true,
+		},
+		{
+			name:     "undo words",
+			content:  "",
+			msgs:     typed("echo hello world"),
+			undo:     2,
+			want:     "echo hello",
+			modified: true,
+		},
+		{
+			name:    "undo all",
+			content: "x",
+			msgs:    concat(typed("echo hello"), []tea.Msg{backspace, backspace}),
+			undo:    4,
+			want:    "x",
+		},
+		{
+			name:     "undo deletes",
+			content:  "x",
+			msgs:     concat(typed("echo hello"), []tea.Msg{backspace, backspace}),
+			undo:     1,
+			want:     "echo hellox",
+			modified: true,
+		},
+		{
+			name:     "cursor moves end edits",
+			content:  "x",
+			msgs:     concat(typed("ab"), []tea.Msg{end}, typed("cd")),
+			undo:     1,
+			want:     "abx",
+			modified: true,
+		},
+		{
+			name:     "redo",
+			content:  "",
+			msgs:     typed("echo hello world"),
+			undo:     3,
+			redo:     2,
+			want:     "echo hello ",
+			modified: true,
+		},
+		{
+			name:     "undo restores cursor",
+			content:  "one\ntwo",
+			msgs:     concat([]tea.Msg{tea.KeyMsg{Type: tea.KeyDown}, end}, typed("!"), typed(" ")),
+			undo:     1,
+			want:     "one\ntwo!",
+			modified: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newContentEditor(store.Snippet{Language: "go"}, tc.content)
+			for _, msg := range tc.msgs {
+				e.update(msg)
+			}
+			for i := 0; i < tc.undo; i++ {
+				e.undoEdit()
+			}
+			for i := 0; i < tc.redo; i++ {
+				e.redoEdit()
+			}
+			if got := e.content(); got != tc.want {
+				t.Logf("content is incorrect: want %q but got %q", tc.want, got)
+				t.FailNow()
+			}
+			if e.modified() != tc.modified {
+				t.Logf("modified is incorrect: want %v", tc.modified)
+				t.FailNow()
+			}
+		})
+	}
+
+	t.Run("cursor after undo", func(t *testing.T) {
+		e := newContentEditor(store.Snippet{}, "one\ntwo")
+		for _, msg := range concat([]tea.Msg{tea.KeyMsg{Type: tea.KeyDown}, end}, typed("!")) {
+			e.update(msg)
+		}
+		e.undoEdit()
+		e.update(runes("?"))
+		if got, want := e.content(), "one\ntwo?"; got != want {
+			t.Logf("content is incorrect: want %q but got %q", want, got)
+			t.FailNow()
+		}
+	})
+}
+
+func TestRenderLine(t *testing.T) {
+	tt := []struct {
+		name   string
+		line   string
+		cursor int
+		left   int
+		width  int
+		want   string
+	}{
+		{
+			name:   "no cursor",
+			line:   "abc",
+			cursor: -1,
+			width:  10,
+			want:   "abc\x1b[0m",
+		},
+		{
+			name:   "cursor",
+			line:   "\x1b[31mab\x1b[0mc",
+			cursor: 1,
+			width:  10,
+			want:   "\x1b[31ma\x1b[7mb\x1b[27m\x1b[0mc\x1b[0m",
+		},
+		{
+			name:   "cursor at end",
+			line:   "ab",
+			cursor: 2,
+			width:  10,
+			want:   "ab\x1b[7m \x1b[27m\x1b[0m",
+		},
+		{
+			name:   "tab",
+			line:   "\tx",
+			cursor: -1,
+			width:  10,
+			want:   "    x\x1b[0m",
+		},
+		{
+			name:   "scrolled",
+			line:   "abcdefgh",
+			cursor: 5,
+			left:   3,
+			width:  3,
+			want:   "de\x1b[7mf\x1b[27m\x1b[0m",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderLine(tc.line, tc.cursor, tc.left, tc.width); got != tc.want {
+				t.Logf("line is incorrect: want %q but got %q", tc.want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
@@ -3,22 +3,100 @@ package main
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"golang.org/x/exp/slices"
 )
 
 const defaultEditor = "nano"
 
-// Cmd returns a *exec.Cmd editing the given path with $EDITOR or nano if no
-// $EDITOR is set.
-func editorCmd(path string) *exec.Cmd {
-	editor, args := getEditor()
-	return exec.Command(editor, append(args, path)...)
+// EditorProfile tells how to open a file at a given line with an editor and
+// how to make it wait for the file to be closed.
+type EditorProfile struct {
+	// Line are the arguments opening the file at a line, in which {file} and
+	// {line} are replaced. Without them, the file is opened at its start.
+	Line []string `yaml:"line"`
+	// Wait is the flag making a graphical editor wait for the file to be
+	// closed before exiting. It is added unless it is already given.
+	Wait string `yaml:"wait"`
+}
+
+// editorProfiles are the profiles of common editors, by command name. They
+// are extended and overridden by the editors of the configuration.
+var editorProfiles = map[string]EditorProfile{
+	"vi":            {Line: []string{"+{line}", "{file}"}},
+	"vim":           {Line: []string{"+{line}", "{file}"}},
+	"nvim":          {Line: []string{"+{line}", "{file}"}},
+	"nano":          {Line: []string{"+{line}", "{file}"}},
+	"micro":         {Line: []string{"+{line}", "{file}"}},
+	"kak":           {Line: []string{"+{line}", "{file}"}},
+	"emacs":         {Line: []string{"+{line}", "{file}"}},
+	"emacsclient":   {Line: []string{"+{line}", "{file}"}},
+	"hx":            {Line: []string{"{file}:{line}"}},
+	"helix":         {Line: []string{"{file}:{line}"}},
+	"code":          {Line: []string{"--goto", "{file}:{line}"}, Wait: "--wait"},
+	"code-insiders": {Line: []string{"--goto", "{file}:{line}"}, Wait: "--wait"},
+	"codium":        {Line: []string{"--goto", "{file}:{line}"}, Wait: "--wait"},
+	"subl":          {Line: []string{"{file}:{line}"}, Wait: "--wait"},
+	"zed":           {Line: []string{"{file}:{line}"}, Wait: "--wait"},
 }
 
-func getEditor() (string, []string) {
-	editor := strings.Fields(os.Getenv("EDITOR"))
-	if len(editor) > 0 {
-		return editor[0], editor[1:]
+// editorCmd returns a *exec.Cmd editing the given path with the editor of
+// the configuration, $VISUAL, $EDITOR or nano, in this order, at the given
+// line if it is positive and the editor supports it.
+func editorCmd(config Config, path string, line int) *exec.Cmd {
+	editor, args := getEditor(config)
+	profile := editorProfile(config, editor)
+	if profile.Wait != "" && !slices.Contains(args, profile.Wait) {
+		args = append(args, profile.Wait)
+	}
+	if line <= 0 || len(profile.Line) == 0 {
+		return exec.Command(editor, append(args, path)...)
+	}
+	replacer := strings.NewReplacer("{file}", path, "{line}", strconv.Itoa(line))
+	for _, arg := range profile.Line {
+		args = append(args, replacer.Replace(arg))
+	}
+	return exec.Command(editor, args...)
+}
+
+// getEditor returns the command and arguments of the editor.
+func getEditor(config Config) (string, []string) {
+	for _, editor := range []string{config.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if fields := strings.Fields(editor); len(fields) > 0 {
+			return fields[0], fields[1:]
+		}
 	}
 	return defaultEditor, nil
 }
+
+// editorProfile returns the profile of the editor command, if any.
+func editorProfile(config Config, editor string) EditorProfile {
+	name := strings.TrimSuffix(filepath.Base(editor), ".exe")
+	if profile, ok := config.Editors[name]; ok {
+		return profile
+	}
+	return editorProfiles[name]
+}
+
+// hasEditor returns whether the editor is installed.
+func hasEditor(config Config) bool {
+	editor, _ := getEditor(config)
+	_, err := exec.LookPath(editor)
+	return err == nil
+}
+
+// splitLine splits a trailing :line from a snippet, as printed by nap search.
+func splitLine(search string) (string, int) {
+	i := strings.LastIndex(search, ":")
+	if i < 0 {
+		return search, 0
+	}
+	line, err := strconv.Atoi(search[i+1:])
+	if err != nil || line <= 0 {
+		return search, 0
+	}
+	return search[:i], line
+}
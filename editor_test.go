@@ -2,13 +2,14 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"testing"
 )
 
 func TestGetEditor(t *testing.T) {
 	tt := []struct {
 		Name      string
+		Config    string
+		VisualEnv string
 		EditorEnv string
 		Cmd       string
 		Args      []string
@@ -34,23 +35,28 @@ func TestGetEditor(t *testing.T) {
 			Cmd:       "code",
 			Args:      []string{"-w"},
 		},
+		{
+			Name:      "visual",
+			VisualEnv: "hx",
+			EditorEnv: "vim",
+			Cmd:       "hx",
+		},
+		{
+			Name:      "config",
+			Config:    "micro -autosave 1",
+			VisualEnv: "hx",
+			EditorEnv: "vim",
+			Cmd:       "micro",
+			Args:      []string{"-autosave", "1"},
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
-			var err error
-			switch tc.EditorEnv {
-			case "":
-				err = os.Unsetenv("EDITOR")
-			default:
-				err = os.Setenv("EDITOR", tc.EditorEnv)
-			}
-			if err != nil {
-				t.Logf("could not (un)set env: %v", err)
-				t.FailNow()
-			}
+			t.Setenv("VISUAL", tc.VisualEnv)
+			t.Setenv("EDITOR", tc.EditorEnv)
 
-			cmd, args := getEditor()
+			cmd, args := getEditor(Config{Editor: tc.Config})
 
 			if cmd != tc.Cmd {
 				t.Logf("cmd is incorrect: want %q but got %q", tc.Cmd, cmd)
@@ -64,3 +70,95 @@ func TestGetEditor(t *testing.T) {
 		})
 	}
 }
+
+func TestEditorCmd(t *testing.T) {
+	tt := []struct {
+		Name    string
+		Editor  string
+		Editors map[string]EditorProfile
+		Line    int
+		Args    []string
+	}{
+		{
+			Name:   "no line",
+			Editor: "vim",
+			Args:   []string{"vim", "a.go"},
+		},
+		{
+			Name:   "vim",
+			Editor: "vim -u NONE",
+			Line:   12,
+			Args:   []string{"vim", "-u", "NONE", "+12", "a.go"},
+		},
+		{
+			Name:   "helix",
+			Editor: "/usr/bin/hx",
+			Line:   3,
+			Args:   []string{"/usr/bin/hx", "a.go:3"},
+		},
+		{
+			Name:   "code waits",
+			Editor: "code",
+			Args:   []string{"code", "--wait", "a.go"},
+		},
+		{
+			Name:   "code at line",
+			Editor: "code --wait -n",
+			Line:   7,
+			Args:   []string{"code", "--wait", "-n", "--goto", "a.go:7"},
+		},
+		{
+			Name:   "unknown editor",
+			Editor: "ed",
+			Line:   7,
+			Args:   []string{"ed", "a.go"},
+		},
+		{
+			Name:    "configured profile",
+			Editor:  "vim",
+			Editors: map[string]EditorProfile{"vim": {Line: []string{"{file}", "-c", "{line}"}}},
+			Line:    5,
+			Args:    []string{"vim", "a.go", "-c", "5"},
+		},
+		{
+			Name:    "configured editor",
+			Editor:  "ed",
+			Editors: map[string]EditorProfile{"ed": {Wait: "-w"}},
+			Line:    5,
+			Args:    []string{"ed", "-w", "a.go"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			cmd := editorCmd(Config{Editor: tc.Editor, Editors: tc.Editors}, "a.go", tc.Line)
+			if got, want := fmt.Sprint(cmd.Args), fmt.Sprint(tc.Args); got != want {
+				t.Logf("args are incorrect: want %q but got %q", want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	tt := []struct {
+		Search  string
+		Snippet string
+		Line    int
+	}{
+		{"misc/hello.go", "misc/hello.go", 0},
+		{"misc/hello.go:12", "misc/hello.go", 12},
+		{"hello:0", "hello:0", 0},
+		{"a:b", "a:b", 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Search, func(t *testing.T) {
+			snippet, line := splitLine(tc.Search)
+			if snippet != tc.Snippet || line != tc.Line {
+				t.Logf("split is incorrect: want %q, %d but got %q, %d", tc.Snippet, tc.Line, snippet, line)
+				t.FailNow()
+			}
+		})
+	}
+}
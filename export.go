@@ -0,0 +1,268 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/exp/slices"
+)
+
+// bundleMetadata is the name of the metadata file in exported archives.
+const bundleMetadata = "snippets.json"
+
+// exporters write snippets in a portable format.
+var exporters = map[string]func(w io.Writer, st *store.Store, snippets []store.Snippet) error{
+	"tar":      exportTar,
+	"zip":      exportZip,
+	"markdown": exportMarkdown,
+	"vscode":   exportVSCode,
+}
+
+// runExport exports the snippets of the given folders, or all of them, to an
+// archive which can be imported by nap, a Markdown document or a VS Code
+// snippets file.
+func runExport(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var filter snippetFilter
+	var format, output string
+	flags := newFlagSet("export")
+	filter.register(flags)
+	flags.StringVar(&format, "format", "", "format of the export: tar, zip, markdown or vscode")
+	flags.StringVar(&output, "output", "", "file to write the export to instead of stdout")
+	flags.StringVar(&output, "o", "", "file to write the export to instead of stdout")
+	folders, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		format = exportFormat(output)
+	}
+	export, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("invalid format %q: use tar, zip, markdown or vscode", format)
+	}
+	snippets = inFolders(filter.apply(snippets), folders)
+	if len(snippets) == 0 {
+		return errors.New("no snippets to export")
+	}
+
+	if output == "" {
+		if (format == "tar" || format == "zip") && isatty.IsTerminal(os.Stdout.Fd()) {
+			return errors.New("refusing to write an archive to the terminal, use --output")
+		}
+		return export(w, st, snippets)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := export(f, st, snippets); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Exported %d snippets to %s\n", len(snippets), output)
+	return nil
+}
+
+// exportFormat returns the format of an export from the extension of the
+// output file, Markdown by default.
+func exportFormat(output string) string {
+	switch {
+	case strings.HasSuffix(output, ".tar.gz"), strings.HasSuffix(output, ".tgz"):
+		return "tar"
+	case strings.HasSuffix(output, ".zip"):
+		return "zip"
+	case strings.HasSuffix(output, ".code-snippets"), strings.HasSuffix(output, ".json"):
+		return "vscode"
+	}
+	return "markdown"
+}
+
+// bundleFile is a file of an exported archive.
+type bundleFile struct {
+	name    string
+	content []byte
+	date    time.Time
+}
+
+// bundle returns the files of an archive with the snippets: the metadata
+// followed by the content of each snippet at its <folder>/<file> path.
+func bundle(st *store.Store, snippets []store.Snippet) ([]bundleFile, error) {
+	metadata, err := json.Marshal(snippets)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal snippets: %w", err)
+	}
+	files := []bundleFile{{name: bundleMetadata, content: metadata, date: time.Now()}}
+	for _, snippet := range snippets {
+		content, err := st.Content(snippet)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", snippet, err)
+		}
+		files = append(files, bundleFile{name: path.Join(snippet.Folder, snippet.File), content: content, date: snippet.Modified})
+	}
+	return files, nil
+}
+
+// exportTar writes the snippets as a gzipped tar archive.
+func exportTar(w io.Writer, st *store.Store, snippets []store.Snippet) error {
+	files, err := bundle(st, snippets)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0o644,
+			Size:    int64(len(file.content)),
+			ModTime: file.date,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// exportZip writes the snippets as a zip archive.
+func exportZip(w io.Writer, st *store.Store, snippets []store.Snippet) error {
+	files, err := bundle(st, snippets)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: file.date})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(file.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// exportMarkdown writes the snippets as a Markdown document with a section
+// per folder and a fenced code block per snippet.
+func exportMarkdown(w io.Writer, st *store.Store, snippets []store.Snippet) error {
+	snippets = slices.Clone(snippets)
+	slices.SortStableFunc(snippets, func(a, b store.Snippet) int {
+		return compareFolders(Folder(a.Folder), Folder(b.Folder))
+	})
+
+	var b strings.Builder
+	for i, snippet := range snippets {
+		if i == 0 || snippet.Folder != snippets[i-1].Folder {
+			fmt.Fprintf(&b, "# %s\n\n", snippet.Folder)
+		}
+		content, err := st.Content(snippet)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", snippet, err)
+		}
+		fmt.Fprintf(&b, "## %s\n\n", snippet.Name)
+		if len(snippet.Tags) > 0 {
+			fmt.Fprintf(&b, "%s\n\n", tagsString(snippet.Tags))
+		}
+		fence := markdownFence(string(content))
+		fmt.Fprintf(&b, "%s%s\n%s\n%s\n\n", fence, snippet.Language, strings.TrimRight(string(content), "\n"), fence)
+	}
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+// markdownFence returns a code fence longer than any run of backticks in the
+// content, so that it cannot end the code block early.
+func markdownFence(content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// vscodeExport is a snippet of a VS Code snippets file.
+type vscodeExport struct {
+	Scope       string   `json:"scope,omitempty"`
+	Prefix      string   `json:"prefix"`
+	Body        []string `json:"body"`
+	Description string   `json:"description"`
+}
+
+// vscodeLanguages maps file extensions to the language identifiers of VS
+// Code, where they differ.
+var vscodeLanguages = map[string]string{
+	"cs":  "csharp",
+	"hs":  "haskell",
+	"js":  "javascript",
+	"jsx": "javascriptreact",
+	"kt":  "kotlin",
+	"md":  "markdown",
+	"py":  "python",
+	"rb":  "ruby",
+	"rs":  "rust",
+	"sh":  "shellscript",
+	"ts":  "typescript",
+	"tsx": "typescriptreact",
+	"txt": "plaintext",
+}
+
+// exportVSCode writes the snippets as a VS Code .code-snippets file, keyed by
+// folder/name.language, or by the path of their file for snippets sharing
+// it, and scoped to their language. Dollar signs are escaped so that VS Code
+// does not read them as tab stops.
+func exportVSCode(w io.Writer, st *store.Store, snippets []store.Snippet) error {
+	export := make(map[string]vscodeExport)
+	names := make(map[string]int)
+	for _, snippet := range snippets {
+		names[snippet.String()]++
+	}
+	for _, snippet := range snippets {
+		content, err := st.Content(snippet)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", snippet, err)
+		}
+		scope := snippet.Language
+		if language, ok := vscodeLanguages[scope]; ok {
+			scope = language
+		}
+		body := strings.ReplaceAll(strings.TrimRight(string(content), "\n"), "$", `\$`)
+		key := snippet.String()
+		if names[key] > 1 {
+			key = snippet.Path()
+		}
+		for n := 2; ; n++ {
+			if _, ok := export[key]; !ok {
+				break
+			}
+			key = fmt.Sprintf("%s (%d)", snippet.Path(), n)
+		}
+		export[key] = vscodeExport{
+			Scope:       scope,
+			Prefix:      snippet.Name,
+			Body:        strings.Split(body, "\n"),
+			Description: snippet.String(),
+		}
+	}
+	return writeJSON(w, export)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+)
+
+func TestExport(t *testing.T) {
+	st := store.New(t.TempDir(), "snippets.json")
+	date := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, snippet := range []store.Snippet{
+		{Folder: "work/go", Name: "server", File: "server.go", Language: "go", Tags: []string{"http"}, Favorite: true, Created: date, Modified: date},
+		{Folder: "misc", Name: "price", File: "price.sh", Language: "sh", Tags: []string{}, Created: date, Modified: date},
+	} {
+		content := "echo $PRICE\n"
+		if snippet.Language == "go" {
+			content = "package main\n\n// ```\n"
+		}
+		if err := st.Create(snippet, []byte(content)); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	snippets, err := st.List()
+	if err != nil {
+		t.Logf("could not list snippets: %v", err)
+		t.FailNow()
+	}
+
+	var b strings.Builder
+	if err := exportMarkdown(&b, st, snippets); err != nil {
+		t.Logf("could not export markdown: %v", err)
+		t.FailNow()
+	}
+	want := "# misc\n\n## price\n\n```sh\necho $PRICE\n```\n\n# work/go\n\n## server\n\n#http\n\n````go\npackage main\n\n// ```\n````\n"
+	if b.String() != want {
+		t.Logf("markdown is incorrect: want %q but got %q", want, b.String())
+		t.FailNow()
+	}
+
+	for _, format := range []string{"tar.gz", "zip", "code-snippets"} {
+		t.Run(format, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "export."+format)
+			var out strings.Builder
+			if err := runExport(&out, st, Config{}, snippets, []string{"-o", output}); err != nil {
+				t.Logf("could not export: %v", err)
+				t.FailNow()
+			}
+
+			imported := store.New(t.TempDir(), "snippets.json")
+			out.Reset()
+			if err := runImport(&out, imported, []string{output}); err != nil {
+				t.Logf("could not import: %v", err)
+				t.FailNow()
+			}
+			snippets, _ := imported.List()
+			var got []string
+			for _, snippet := range snippets {
+				content, _ := imported.Content(snippet)
+				got = append(got, fmt.Sprintf("%s %v %t %q", snippet, snippet.Tags, snippet.Favorite, content))
+			}
+			want := "misc/price.sh [] false \"echo $PRICE\\n\"\nwork/go/server.go [http] true \"package main\\n\\n// ```\\n\""
+			if format == "code-snippets" {
+				// VS Code snippets do not keep tags and favorites.
+				want = strings.Replace(want, "[http] true", "[] false", 1)
+			}
+			if strings.Join(got, "\n") != want {
+				t.Logf("imported snippets are incorrect: want %q but got %q", want, strings.Join(got, "\n"))
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestExportVSCodeSameName(t *testing.T) {
+	st := store.New(t.TempDir(), "snippets.json")
+	for _, snippet := range []store.Snippet{
+		{Folder: "misc", Name: "hello", File: "hello.go", Language: "go", Tags: []string{}},
+		{Folder: "misc", Name: "hello", File: "hello-2.go", Language: "go", Tags: []string{}},
+	} {
+		if err := st.Create(snippet, []byte("package "+strings.TrimSuffix(snippet.File, ".go"))); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	snippets, _ := st.List()
+
+	var b strings.Builder
+	if err := exportVSCode(&b, st, snippets); err != nil {
+		t.Logf("could not export snippets: %v", err)
+		t.FailNow()
+	}
+	var export map[string]vscodeExport
+	if err := json.Unmarshal([]byte(b.String()), &export); err != nil || len(export) != 2 {
+		t.Logf("every snippet should be exported: got %v (%v)", export, err)
+		t.FailNow()
+	}
+	for _, key := range []string{"misc/hello.go", "misc/hello-2.go"} {
+		if _, ok := export[key]; !ok {
+			t.Logf("snippet %q is missing: got %v", key, export)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/maaslalani/nap/store"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// finderWidth and finderHeight are the maximum size of the finder popup.
+const (
+	finderWidth  = 120
+	finderHeight = 30
+)
+
+// finder is a popup to fuzzy find a snippet across every folder.
+type finder struct {
+	input    textinput.Model
+	snippets []store.Snippet
+	matches  []fuzzy.Match
+	cursor   int
+	// offset is the first match shown, scrolled to keep the cursor visible.
+	offset int
+}
+
+// newFinder returns a finder with an input that has the given style.
+func newFinder(style lipgloss.Style) finder {
+	input := newTextInput("Find snippets")
+	input.Prompt = "> "
+	input.PromptStyle = style
+	return finder{input: input}
+}
+
+// filter updates the matches to the snippets fuzzy matching the input. An
+// empty input matches every snippet. Equal matches are listed by frecency.
+func (f *finder) filter() {
+	query := f.input.Value()
+	if query == "" {
+		f.matches = make([]fuzzy.Match, len(f.snippets))
+		for i, snippet := range f.snippets {
+			f.matches[i] = fuzzy.Match{Str: snippet.String(), Index: i}
+		}
+	} else {
+		f.matches = fuzzy.FindFrom(query, Snippets{f.snippets})
+	}
+	rankMatches(f.matches, f.snippets, time.Now())
+	f.cursor, f.offset = 0, 0
+}
+
+// scroll returns the matches to show in the given number of rows, scrolling
+// them so that the cursor is in view.
+func (f *finder) scroll(rows int) []fuzzy.Match {
+	if rows < 1 {
+		rows = 1
+	}
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	}
+	if f.cursor >= f.offset+rows {
+		f.offset = f.cursor - rows + 1
+	}
+	end := f.offset + rows
+	if end > len(f.matches) {
+		end = len(f.matches)
+	}
+	return f.matches[f.offset:end]
+}
+
+// selected returns the snippet under the cursor, if any.
+func (f *finder) selected() (store.Snippet, bool) {
+	if f.cursor < 0 || f.cursor >= len(f.matches) {
+		return store.Snippet{}, false
+	}
+	return f.snippets[f.matches[f.cursor].Index], true
+}
+
+// openFinder opens the finder over the snippets of every folder.
+func (m *Model) openFinder() tea.Cmd {
+	var snippets []store.Snippet
+	folders := maps.Keys(m.Lists)
+	slices.Sort(folders)
+	for _, folder := range folders {
+		for _, item := range m.Lists[folder].Items() {
+			if snippet, ok := item.(store.Snippet); ok {
+				snippets = append(snippets, snippet)
+			}
+		}
+	}
+
+	m.finder.snippets = snippets
+	m.finder.input.SetValue("")
+	m.finder.filter()
+	m.state = findingState
+	m.updateKeyMap()
+	return m.finder.input.Focus()
+}
+
+// updateFinder handles the key presses while the finder is open.
+func (m *Model) updateFinder(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.finder.input.Blur()
+		return changeState(navigatingState)
+	case "enter":
+		m.finder.input.Blur()
+		if snippet, ok := m.finder.selected(); ok {
+			m.selectSnippet(snippet)
+		}
+		return tea.Batch(changeState(navigatingState), m.updateContent())
+	case "up", "ctrl+p", "ctrl+k":
+		if m.finder.cursor > 0 {
+			m.finder.cursor--
+		}
+		return nil
+	case "down", "ctrl+n", "ctrl+j":
+		if m.finder.cursor < len(m.finder.matches)-1 {
+			m.finder.cursor++
+		}
+		return nil
+	}
+
+	query := m.finder.input.Value()
+	var cmd tea.Cmd
+	m.finder.input, cmd = m.finder.input.Update(msg)
+	if m.finder.input.Value() != query {
+		m.finder.filter()
+	}
+	return cmd
+}
+
+// selectSnippet selects the folder of the snippet and the snippet in it.
+func (m *Model) selectSnippet(snippet store.Snippet) {
+	folder := Folder(snippet.Folder)
+	m.expandFolder(folder)
+	m.Folders.SetItems(m.folderItems())
+	m.selectFolder(folder)
+	m.refreshView()
+	for i, item := range m.List().Items() {
+		if s, ok := item.(store.Snippet); ok && s.Same(snippet) {
+			m.List().Select(i)
+			break
+		}
+	}
+	m.pane = snippetPane
+}
+
+// finderView renders the finder popup in the middle of the screen.
+func (m *Model) finderView() string {
+	width := finderWidth
+	if m.width-4 < width {
+		width = m.width - 4
+	}
+	height := finderHeight
+	if m.height < height {
+		height = m.height
+	}
+	listWidth := width / 3
+	styles := DefaultStyles(m.config).Finder
+
+	var results strings.Builder
+	for i, match := range m.finder.scroll(height - 2) {
+		style := styles.Unselected
+		prefix := "  "
+		if m.finder.offset+i == m.finder.cursor {
+			style = styles.Selected
+			prefix = "→ "
+		}
+		results.WriteString(style.Render(prefix) + fuzzyHighlight(match, style, styles.Match, listWidth-2))
+		results.WriteString("\n")
+	}
+	if len(m.finder.matches) == 0 {
+		results.WriteString(styles.Unselected.Render("  No snippets found."))
+	}
+
+	var preview string
+	if snippet, ok := m.finder.selected(); ok {
+		content, err := m.store.Content(snippet)
+		if err == nil {
+			lines := strings.Split(highlightContent(string(content), snippet.Language, m.config.Theme), "\n")
+			if len(lines) > height {
+				lines = lines[:height]
+			}
+			preview = strings.ReplaceAll(strings.Join(lines, "\n"), "\t", strings.Repeat(" ", tabSpaces))
+		}
+	}
+
+	popup := styles.Base.Width(width).Height(height).Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.finder.input.View(),
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Top,
+			lipgloss.NewStyle().Width(listWidth).Height(height-2).Render(results.String()),
+			lipgloss.NewStyle().Width(width-listWidth-2).Height(height-2).MaxHeight(height-2).MaxWidth(width-listWidth-2).Render(preview),
+		),
+	))
+	return lipgloss.Place(m.width, m.height+4, lipgloss.Center, lipgloss.Center, popup)
+}
+
+// fuzzyHighlight renders the string of the match, truncated to width, with
+// the matched characters in the match style.
+func fuzzyHighlight(match fuzzy.Match, style, matchStyle lipgloss.Style, width int) string {
+	var b strings.Builder
+	runes := []rune(match.Str)
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	matched := map[int]bool{}
+	for _, idx := range match.MatchedIndexes {
+		matched[idx] = true
+	}
+	var offset int
+	for _, r := range runes {
+		s := string(r)
+		if matched[offset] {
+			b.WriteString(matchStyle.Render(s))
+		} else {
+			b.WriteString(style.Render(s))
+		}
+		offset += len(s)
+	}
+	return b.String()
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maaslalani/nap/store"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// parent returns the folder the folder is nested in, if any.
+func (f Folder) parent() (Folder, bool) {
+	i := strings.LastIndex(string(f), "/")
+	if i < 0 {
+		return "", false
+	}
+	return f[:i], true
+}
+
+// depth returns how deep the folder is nested, 0 for a top-level folder.
+func (f Folder) depth() int {
+	return strings.Count(string(f), "/")
+}
+
+// name returns the name of the folder without its parents.
+func (f Folder) name() string {
+	return string(f[strings.LastIndex(string(f), "/")+1:])
+}
+
+// contains returns whether the other folder is nested in the folder, at any
+// depth.
+func (f Folder) contains(other Folder) bool {
+	return strings.HasPrefix(string(other), string(f)+"/")
+}
+
+// compareFolders orders folders as a tree, each folder followed by its nested
+// folders, by comparing their names level by level.
+func compareFolders(a, b Folder) int {
+	as, bs := strings.Split(string(a), "/"), strings.Split(string(b), "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// folders returns every folder in tree order. Each folder has a list, which
+// is added for the parents of nested folders without snippets of their own.
+func (m *Model) folders() []Folder {
+	for _, folder := range maps.Keys(m.Lists) {
+		for parent, ok := folder.parent(); ok; parent, ok = parent.parent() {
+			if _, exists := m.Lists[parent]; !exists {
+				m.Lists[parent] = newList([]list.Item{}, m.height, m.ListStyle)
+			}
+		}
+	}
+	folders := maps.Keys(m.Lists)
+	slices.SortFunc(folders, compareFolders)
+	return folders
+}
+
+// visibleFolders returns the folders shown in the folder pane, leaving out
+// the ones nested in a collapsed folder. Folders which no longer have nested
+// folders are not collapsed anymore.
+func (m *Model) visibleFolders() []Folder {
+	folders := m.folders()
+	var visible []Folder
+	for i, folder := range folders {
+		if m.collapsed[folder] && (i+1 == len(folders) || !folder.contains(folders[i+1])) {
+			delete(m.collapsed, folder)
+		}
+		hidden := false
+		for parent, ok := folder.parent(); ok; parent, ok = parent.parent() {
+			if m.collapsed[parent] {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			visible = append(visible, folder)
+		}
+	}
+	return visible
+}
+
+// toggleFolder collapses the selected folder, hiding its nested folders, or
+// expands it again.
+func (m *Model) toggleFolder() {
+	folder, ok := m.Folders.SelectedItem().(Folder)
+	if !ok {
+		return
+	}
+	if m.collapsed[folder] {
+		delete(m.collapsed, folder)
+	} else if slices.ContainsFunc(m.folders(), folder.contains) {
+		m.collapsed[folder] = true
+	}
+	m.Folders.SetItems(m.folderItems())
+	m.selectFolder(folder)
+}
+
+// expandFolder expands the parents of the folder so that it is shown.
+func (m *Model) expandFolder(folder Folder) {
+	for parent, ok := folder.parent(); ok; parent, ok = parent.parent() {
+		delete(m.collapsed, parent)
+	}
+}
+
+// selectFolder selects the folder in the folder pane.
+func (m *Model) selectFolder(folder Folder) {
+	for i, item := range m.Folders.Items() {
+		if f, ok := item.(Folder); ok && f == folder {
+			m.Folders.Select(i)
+			return
+		}
+	}
+}
+
+// folderAction is an operation on folders waiting for a folder name.
+type folderAction int
+
+const (
+	createFolderAction folderAction = iota
+	renameFolderAction
+	mergeFolderAction
+)
+
+// editFolder asks for the name of a folder to create, or for the folder to
+// rename the selected folder to or to merge it into.
+func (m *Model) editFolder(action folderAction) tea.Cmd {
+	m.folderAction = action
+	m.folderInput.Reset()
+	switch action {
+	case createFolderAction:
+		m.folderInput.Placeholder = "New folder"
+	case renameFolderAction:
+		m.folderInput.Placeholder = "Rename to"
+		m.folderInput.SetValue(string(m.selectedFolder()))
+		m.folderInput.CursorEnd()
+	case mergeFolderAction:
+		m.folderInput.Placeholder = "Merge into"
+	}
+	return changeState(editingFolderState)
+}
+
+// updateFolderInput handles the key presses while typing a folder name.
+func (m *Model) updateFolderInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.folderInput.Blur()
+		return changeState(navigatingState)
+	case "enter":
+		m.folderInput.Blur()
+		name := Folder(strings.Trim(strings.TrimSpace(m.folderInput.Value()), "/"))
+		if name == "" {
+			return changeState(navigatingState)
+		}
+		var err error
+		switch m.folderAction {
+		case createFolderAction:
+			err = m.createFolder(name)
+		case renameFolderAction:
+			err = m.moveFolder(m.selectedFolder(), name, false)
+		case mergeFolderAction:
+			err = m.moveFolder(m.selectedFolder(), name, true)
+		}
+		if err != nil {
+			m.displayError("Unable to update folder: " + err.Error())
+			return changeState(navigatingState)
+		}
+		return tea.Batch(changeState(navigatingState), m.updateContent())
+	}
+	var cmd tea.Cmd
+	m.folderInput, cmd = m.folderInput.Update(msg)
+	return cmd
+}
+
+// createFolder creates an empty folder and selects it.
+func (m *Model) createFolder(folder Folder) error {
+	if err := m.store.CreateFolder(string(folder)); err != nil {
+		return err
+	}
+	if _, ok := m.Lists[folder]; !ok {
+		m.Lists[folder] = newList([]list.Item{}, m.height, m.ListStyle)
+	}
+	m.expandFolder(folder)
+	m.Folders.SetItems(m.folderItems())
+	m.selectFolder(folder)
+	return nil
+}
+
+// moveFolder renames the folder, or merges it into another one, along with
+// its nested folders and selects it at its new location.
+func (m *Model) moveFolder(from, to Folder, merge bool) error {
+	var err error
+	if merge {
+		err = m.store.MergeFolder(string(from), string(to))
+	} else {
+		err = m.store.RenameFolder(string(from), string(to))
+	}
+	if err != nil {
+		return err
+	}
+
+	// The lists are all taken out before being moved, as the new location
+	// of a nested folder may be the old location of another one.
+	moved := make(map[Folder]*list.Model)
+	for folder, li := range m.Lists {
+		if folder == from || from.contains(folder) {
+			moved[folder] = li
+			delete(m.Lists, folder)
+		}
+	}
+	for folder, li := range moved {
+		target := to + folder[len(from):]
+		if _, ok := m.Lists[target]; !ok {
+			m.Lists[target] = newList([]list.Item{}, m.height, m.ListStyle)
+		}
+		for _, item := range li.Items() {
+			if snippet, ok := item.(store.Snippet); ok {
+				snippet.Folder = string(target)
+				m.Lists[target].InsertItem(len(m.Lists[target].Items()), snippet)
+			}
+		}
+		if m.collapsed[folder] {
+			delete(m.collapsed, folder)
+			m.collapsed[target] = true
+		}
+	}
+	m.expandFolder(to)
+	m.Folders.SetItems(m.folderItems())
+	m.selectFolder(to)
+	return nil
+}
+
+// deleteFolder moves the snippets of the selected folder and its nested
+// folders to the trash and removes the folders.
+func (m *Model) deleteFolder() error {
+	folder := m.selectedFolder()
+	trashed, err := m.store.DeleteFolder(string(folder))
+	for i := len(trashed) - 1; i >= 0; i-- {
+		m.trash = append([]store.Trashed{trashed[i]}, m.trash...)
+	}
+	if err != nil {
+		return err
+	}
+	for f := range m.Lists {
+		if f == folder || folder.contains(f) {
+			delete(m.Lists, f)
+			delete(m.collapsed, f)
+		}
+	}
+	index := m.Folders.Index()
+	m.Folders.SetItems(m.folderItems())
+	if index >= len(m.Folders.Items()) {
+		index = len(m.Folders.Items()) - 1
+	}
+	m.Folders.Select(index)
+	return nil
+}
+
+// updateDeleteFolder handles the key presses while confirming the deletion of
+// the selected folder.
+func (m *Model) updateDeleteFolder(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		if err := m.deleteFolder(); err != nil {
+			m.displayError("Unable to delete folder: " + err.Error())
+			return changeState(navigatingState)
+		}
+		return tea.Batch(changeState(navigatingState), m.updateContent())
+	case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+		return changeState(navigatingState)
+	}
+	return nil
+}
+
+// foldersTitle sets the title of the folder pane, which holds the folder name
+// being typed or the confirmation of a deletion.
+func (m *Model) foldersTitle() {
+	m.Folders.Title = "Folders"
+	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
+	switch m.state {
+	case editingFolderState:
+		m.Folders.Title = m.folderInput.View()
+	case deletingFolderState:
+		m.Folders.Title = "Delete? (y/N)"
+		m.Folders.Styles.TitleBar = m.FoldersStyle.DeletedTitleBar
+	}
+}
+
+// runFolder runs the folder subcommand to list, create, rename, merge or
+// delete folders.
+func runFolder(w io.Writer, st *store.Store, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	usage, ok := map[string]string{
+		"list":   "list",
+		"create": "create <folder>",
+		"rename": "rename <from> <to>",
+		"merge":  "merge <from> <into>",
+		"delete": "delete <folder>",
+	}[args[0]]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown folder command %q", args[0]))
+	}
+	if len(args)-1 != strings.Count(usage, "<") {
+		return errUsage("folder " + usage)
+	}
+
+	switch args[0] {
+	case "list":
+		folders, err := st.Folders()
+		if err != nil {
+			return err
+		}
+		for _, folder := range folders {
+			fmt.Fprintln(w, folder)
+		}
+	case "create":
+		return st.CreateFolder(args[1])
+	case "rename":
+		return st.RenameFolder(args[1], args[2])
+	case "merge":
+		return st.MergeFolder(args[1], args[2])
+	case "delete":
+		trashed, err := st.DeleteFolder(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Moved %d snippet(s) to the trash\n", len(trashed))
+	}
+	return nil
+}
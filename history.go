@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+	"github.com/maaslalani/nap/store"
+)
+
+// diffContext is the number of unchanged lines shown around the changes of a
+// revision.
+const diffContext = 3
+
+// revisionDiff returns the diff of the given revision of the snippet against
+// the revision before it, or against nothing for the first revision.
+func revisionDiff(st *store.Store, snippet store.Snippet, revisions []store.Revision, rev int) ([]string, error) {
+	var previous []byte
+	for i, r := range revisions {
+		if r.Rev != rev {
+			continue
+		}
+		content, err := st.Revision(snippet, rev)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			previous, err = st.Revision(snippet, revisions[i-1].Rev)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return unifiedDiff(diffLines(string(previous), string(content)), diffContext), nil
+	}
+	return nil, fmt.Errorf("%w: %s@%d", store.ErrNoRevision, snippet.Path(), rev)
+}
+
+// printHistory prints the revisions of the snippet, newest first. If rev is
+// set, the changes made by that revision are printed instead.
+func printHistory(w io.Writer, st *store.Store, snippet store.Snippet, rev int, color bool, config Config) error {
+	revisions, err := st.History(snippet)
+	if err != nil {
+		return err
+	}
+	if rev > 0 {
+		lines, err := revisionDiff(st, snippet, revisions, rev)
+		if err != nil {
+			return err
+		}
+		styles := DefaultStyles(config).Content.Focused
+		for _, line := range lines {
+			if color {
+				line = styleDiffLine(line, styles)
+			}
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	}
+
+	if len(revisions) == 0 {
+		return fmt.Errorf("no history for %s", snippet)
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		r := revisions[i]
+		fmt.Fprintf(w, "%d\t%s\t%s\n", r.Rev, r.Date.Format("2006-01-02 15:04:05"), humanize.Bytes(uint64(r.Size)))
+	}
+	return nil
+}
+
+// restoreSnippet restores the snippet to the given revision.
+func restoreSnippet(st *store.Store, snippet store.Snippet, rev int) error {
+	if rev <= 0 {
+		return errors.New("missing revision to restore, use --rev N")
+	}
+	return st.Restore(snippet, rev)
+}
+
+// styleDiffLine colors a line of a unified diff.
+func styleDiffLine(line string, styles ContentBaseStyle) string {
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return styles.DiffHunk.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return styles.DiffInsert.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return styles.DiffDelete.Render(line)
+	}
+	return line
+}
+
+// openHistory shows the changes of the latest revision of the selected
+// snippet in the content pane, to browse and restore its revisions.
+func (m *Model) openHistory() tea.Cmd {
+	revisions, err := m.store.History(m.selectedSnippet())
+	if err != nil {
+		m.displayError("Unable to read history.")
+		return nil
+	}
+	if len(revisions) == 0 {
+		m.displayError("No history yet.")
+		return nil
+	}
+	m.revisions = revisions
+	m.revision = len(revisions) - 1
+	m.pane = contentPane
+	return changeState(historyState)
+}
+
+// updateHistory handles the key presses while browsing the history.
+func (m *Model) updateHistory(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "q", "H":
+		m.pane = snippetPane
+		return tea.Batch(changeState(navigatingState), m.updateContent())
+	case "left", "h":
+		if m.revision > 0 {
+			m.revision--
+			m.displayRevision()
+		}
+		return nil
+	case "right", "l":
+		if m.revision < len(m.revisions)-1 {
+			m.revision++
+			m.displayRevision()
+		}
+		return nil
+	case "enter":
+		if err := m.store.Restore(m.selectedSnippet(), m.revisions[m.revision].Rev); err != nil {
+			m.displayError("Unable to restore revision: " + err.Error())
+			return nil
+		}
+		m.pane = snippetPane
+		return tea.Batch(changeState(navigatingState), m.updateContent())
+	}
+
+	var cmd tea.Cmd
+	m.Code, cmd = m.Code.Update(msg)
+	return cmd
+}
+
+// displayRevision updates the content view with the changes of the selected
+// revision.
+func (m *Model) displayRevision() {
+	lines, err := revisionDiff(m.store, m.selectedSnippet(), m.revisions, m.revisions[m.revision].Rev)
+	if err != nil {
+		m.displayError("Unable to read revision.")
+		return
+	}
+	if len(lines) == 0 {
+		lines = []string{"No changes."}
+	}
+
+	var code strings.Builder
+	for _, line := range lines {
+		code.WriteString(styleDiffLine(line, m.ContentStyle) + "\n")
+	}
+	code.WriteString("\n" + m.historyHints())
+	m.LineNumbers.SetContent("")
+	m.Code.SetContent(code.String())
+	m.Code.GotoTop()
+}
+
+// historyHints returns the keys available while browsing the history.
+func (m *Model) historyHints() string {
+	var hints []string
+	for _, hint := range [][2]string{{"←/→", "revisions"}, {"enter", "restore"}, {"esc", "back"}} {
+		hints = append(hints, m.ContentStyle.EmptyHintKey.Render(hint[0])+" "+m.ContentStyle.EmptyHint.Render("• "+hint[1]))
+	}
+	return strings.Join(hints, "   ")
+}
+
+// historyTitle returns the title of the selected revision.
+func (m *Model) historyTitle() string {
+	r := m.revisions[m.revision]
+	return fmt.Sprintf("Revision %d/%d • %s", r.Rev, m.revisions[len(m.revisions)-1].Rev, humanizeTime(r.Date))
+}
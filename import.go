@@ -0,0 +1,783 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+)
+
+// importers read the snippets of a path in an external format. The folder of
+// the snippets is empty unless the format has folders.
+var importers = map[string]func(path string) ([]store.Import, error){
+	"dir":      store.ReadDir,
+	"vscode":   importVSCode,
+	"gist":     importGist,
+	"pet":      importPet,
+	"masscode": importMassCode,
+	"archive":  importArchive,
+}
+
+// runImport imports snippets from directories and files in other formats.
+func runImport(w io.Writer, st *store.Store, args []string) error {
+	var format, folder string
+	var dryRun bool
+	flags := newFlagSet("import")
+	flags.StringVar(&format, "format", "", "format of the files: dir, vscode, gist, pet, masscode or archive")
+	flags.StringVar(&folder, "folder", "", "folder to import the snippets into")
+	flags.BoolVar(&dryRun, "dry-run", false, "print what would be imported without importing it")
+	paths, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errUsage("import [--format dir|vscode|gist|pet|masscode|archive] [--folder <folder>] [--dry-run] <path>...")
+	}
+	folder = strings.Trim(folder, "/")
+
+	var imports []store.Import
+	for _, path := range paths {
+		pathFormat := format
+		if pathFormat == "" {
+			var err error
+			if pathFormat, err = detectFormat(path); err != nil {
+				return err
+			}
+		}
+		importer, ok := importers[pathFormat]
+		if !ok {
+			return fmt.Errorf("invalid format %q: use dir, vscode, gist, pet, masscode or archive", pathFormat)
+		}
+		found, err := importer(path)
+		if err != nil {
+			return err
+		}
+		imports = append(imports, found...)
+	}
+	for i := range imports {
+		snippet := &imports[i].Snippet
+		switch {
+		case snippet.Folder == "" && folder == "":
+			snippet.Folder = defaultSnippetFolder
+		case snippet.Folder == "":
+			snippet.Folder = folder
+		case folder != "":
+			snippet.Folder = folder + "/" + snippet.Folder
+		}
+	}
+
+	results, err := st.Import(imports, dryRun)
+	var imported, skipped int
+	for _, result := range results {
+		switch {
+		case result.Duplicate != nil:
+			fmt.Fprintf(w, "Skipped %s, same content as %s\n", result.Snippet, result.Duplicate)
+			skipped++
+		case dryRun:
+			fmt.Fprintf(w, "Would import %s\n", result.Snippet)
+			imported++
+		default:
+			fmt.Fprintf(w, "Imported %s\n", result.Snippet)
+			imported++
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d imported, %d skipped\n", imported, skipped)
+	return nil
+}
+
+// detectFormat returns the format of the path from its type and extension,
+// or the content of JSON files.
+func detectFormat(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "dir", nil
+	}
+	if exportFormat(path) == "tar" || exportFormat(path) == "zip" {
+		return "archive", nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".code-snippets":
+		return "vscode", nil
+	case ".toml":
+		return "pet", nil
+	case ".json":
+		data, err := readJSON(path)
+		if err != nil {
+			return "", err
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil {
+			// Gist exports are arrays of gists.
+			return "gist", nil
+		}
+		if _, ok := object["folders"]; ok {
+			return "masscode", nil
+		}
+		if _, ok := object["files"]; ok {
+			return "gist", nil
+		}
+		return "vscode", nil
+	}
+	return "", fmt.Errorf("unknown format of %q, use --format", path)
+}
+
+// vscodeSnippet is a snippet of a VS Code snippets file.
+type vscodeSnippet struct {
+	Scope string          `json:"scope"`
+	Body  json.RawMessage `json:"body"`
+}
+
+// importVSCode reads a VS Code snippets file, either a global .code-snippets
+// file whose snippets have a scope, or a language file such as go.json.
+func importVSCode(path string) ([]store.Import, error) {
+	data, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	var snippets map[string]vscodeSnippet
+	if err := json.Unmarshal(data, &snippets); err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", path, err)
+	}
+
+	fileLanguage := defaultLanguage
+	if ext := filepath.Ext(path); ext == ".json" {
+		fileLanguage = languageExtension(strings.TrimSuffix(filepath.Base(path), ext))
+	}
+	names := make([]string, 0, len(snippets))
+	for name := range snippets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var imports []store.Import
+	for _, name := range names {
+		snippet := snippets[name]
+		var lines []string
+		if err := json.Unmarshal(snippet.Body, &lines); err != nil {
+			var body string
+			if err := json.Unmarshal(snippet.Body, &body); err != nil {
+				return nil, fmt.Errorf("could not parse the body of %q: %w", name, err)
+			}
+			lines = []string{body}
+		}
+		language := fileLanguage
+		if scope, _, _ := strings.Cut(snippet.Scope, ","); scope != "" {
+			language = languageExtension(strings.TrimSpace(scope))
+		}
+		// Snippets exported by nap are named folder/name.language, with
+		// escaped dollar signs.
+		body := strings.ReplaceAll(strings.Join(lines, "\n")+"\n", `\$`, "$")
+		folder := ""
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			folder, name = name[:i], strings.TrimSuffix(name[i+1:], "."+language)
+		}
+		imp := newImport(name, language, body)
+		imp.Snippet.Folder = folder
+		imports = append(imports, imp)
+	}
+	return imports, nil
+}
+
+// gist is a gist as returned by the GitHub API.
+type gist struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Files     map[string]struct {
+		Filename string `json:"filename"`
+		Content  string `json:"content"`
+	} `json:"files"`
+}
+
+// importGist reads a gist, or an array of gists, in the JSON format of the
+// GitHub API, with one snippet per file.
+func importGist(path string) ([]store.Import, error) {
+	data, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	var gists []gist
+	if err := json.Unmarshal(data, &gists); err != nil {
+		var single gist
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("could not parse %q: %w", path, err)
+		}
+		gists = []gist{single}
+	}
+
+	var imports []store.Import
+	for _, g := range gists {
+		names := make([]string, 0, len(g.Files))
+		for name := range g.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			file := g.Files[name]
+			if file.Filename != "" {
+				name = file.Filename
+			}
+			ext := filepath.Ext(name)
+			imp := newImport(strings.TrimSuffix(name, ext), languageExtension(strings.TrimPrefix(ext, ".")), file.Content)
+			imp.Snippet.Created, imp.Snippet.Modified = g.CreatedAt, g.UpdatedAt
+			imports = append(imports, imp)
+		}
+	}
+	return imports, nil
+}
+
+// importPet reads the TOML snippets file of pet, whose snippets are shell
+// commands.
+func importPet(path string) ([]store.Import, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", path, err)
+	}
+
+	var imports []store.Import
+	for _, table := range tables["snippets"] {
+		command, _ := table["command"].(string)
+		name, _ := table["description"].(string)
+		if name == "" {
+			name, _, _ = strings.Cut(command, " ")
+		}
+		imp := newImport(name, "sh", command+"\n")
+		if tags, ok := table["tag"].([]string); ok {
+			imp.Snippet.Tags = tags
+		}
+		imports = append(imports, imp)
+	}
+	return imports, nil
+}
+
+// massCode is the database of massCode.
+type massCode struct {
+	Folders []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		ParentID string `json:"parentId"`
+	} `json:"folders"`
+	Snippets []struct {
+		Name      string   `json:"name"`
+		FolderID  string   `json:"folderId"`
+		TagsIDs   []string `json:"tagsIds"`
+		Favorite  bool     `json:"isFavorites"`
+		Deleted   bool     `json:"isDeleted"`
+		CreatedAt int64    `json:"createdAt"`
+		UpdatedAt int64    `json:"updatedAt"`
+		Content   []struct {
+			Label    string `json:"label"`
+			Language string `json:"language"`
+			Value    string `json:"value"`
+		} `json:"content"`
+	} `json:"snippets"`
+	Tags []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"tags"`
+}
+
+// importMassCode reads the db.json database of massCode, with one snippet per
+// fragment, in nested folders.
+func importMassCode(path string) ([]store.Import, error) {
+	data, err := readJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	var db massCode
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", path, err)
+	}
+
+	names := make(map[string]string)
+	parents := make(map[string]string)
+	for _, folder := range db.Folders {
+		names[folder.ID] = strings.ReplaceAll(folder.Name, "/", "-")
+		parents[folder.ID] = folder.ParentID
+	}
+	folderPath := func(id string) string {
+		var path []string
+		for seen := 0; id != "" && seen < len(db.Folders); seen++ {
+			if name := names[id]; name != "" {
+				path = append([]string{name}, path...)
+			}
+			id = parents[id]
+		}
+		return strings.Join(path, "/")
+	}
+	tags := make(map[string]string)
+	for _, tag := range db.Tags {
+		tags[tag.ID] = tag.Name
+	}
+
+	var imports []store.Import
+	for _, snippet := range db.Snippets {
+		if snippet.Deleted {
+			continue
+		}
+		for _, fragment := range snippet.Content {
+			name := snippet.Name
+			if len(snippet.Content) > 1 && fragment.Label != "" {
+				name += " - " + fragment.Label
+			}
+			imp := newImport(name, languageExtension(fragment.Language), fragment.Value)
+			imp.Snippet.Folder = folderPath(snippet.FolderID)
+			imp.Snippet.Favorite = snippet.Favorite
+			for _, id := range snippet.TagsIDs {
+				if tag := tags[id]; tag != "" {
+					imp.Snippet.Tags = append(imp.Snippet.Tags, tag)
+				}
+			}
+			if snippet.CreatedAt > 0 {
+				imp.Snippet.Created = time.UnixMilli(snippet.CreatedAt)
+			}
+			if snippet.UpdatedAt > 0 {
+				imp.Snippet.Modified = time.UnixMilli(snippet.UpdatedAt)
+			}
+			imports = append(imports, imp)
+		}
+	}
+	return imports, nil
+}
+
+// importArchive reads a tar.gz or zip archive exported by nap, with the
+// metadata of the snippets next to their files.
+func importArchive(path string) ([]store.Import, error) {
+	files := make(map[string][]byte)
+	var err error
+	if exportFormat(path) == "zip" {
+		err = readZip(path, files)
+	} else {
+		err = readTar(path, files)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %w", path, err)
+	}
+	metadata, ok := files[bundleMetadata]
+	if !ok {
+		return nil, fmt.Errorf("could not read %q: %s is missing", path, bundleMetadata)
+	}
+	var snippets []store.Snippet
+	if err := json.Unmarshal(metadata, &snippets); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", bundleMetadata, err)
+	}
+
+	var imports []store.Import
+	for _, snippet := range snippets {
+		content, ok := files[filepath.ToSlash(snippet.Path())]
+		if !ok {
+			return nil, fmt.Errorf("could not read %q: %s is missing", path, snippet.Path())
+		}
+		imports = append(imports, store.Import{Snippet: snippet, Content: content})
+	}
+	return imports, nil
+}
+
+// readTar reads the regular files of a gzipped tar archive into files.
+func readTar(path string, files map[string][]byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if files[header.Name], err = io.ReadAll(tr); err != nil {
+			return err
+		}
+	}
+}
+
+// readZip reads the files of a zip archive into files.
+func readZip(path string, files map[string][]byte) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+		files[file.Name], err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newImport returns a snippet to import with the given name, language and
+// content, without a folder, tags or dates, which are set when it is
+// imported.
+func newImport(name, language, content string) store.Import {
+	if strings.TrimSpace(name) == "" {
+		name = defaultSnippetName
+	}
+	if language == "" {
+		language = defaultLanguage
+	}
+	return store.Import{
+		Snippet: store.Snippet{
+			Name:     name,
+			File:     store.FileName(name, language),
+			Language: language,
+			Tags:     []string{},
+		},
+		Content: []byte(content),
+	}
+}
+
+// languageExtensions maps the language identifiers of other snippet managers
+// to file extensions.
+var languageExtensions = map[string]string{
+	"bash":            "sh",
+	"csharp":          "cs",
+	"golang":          "go",
+	"haskell":         "hs",
+	"javascript":      "js",
+	"javascriptreact": "jsx",
+	"kotlin":          "kt",
+	"markdown":        "md",
+	"plain_text":      "txt",
+	"plaintext":       "txt",
+	"python":          "py",
+	"ruby":            "rb",
+	"rust":            "rs",
+	"shell":           "sh",
+	"shellscript":     "sh",
+	"text":            "txt",
+	"typescript":      "ts",
+	"typescriptreact": "tsx",
+}
+
+// languageExtension returns the file extension of a language identifier.
+func languageExtension(language string) string {
+	language = strings.ToLower(language)
+	if ext, ok := languageExtensions[language]; ok {
+		return ext
+	}
+	return language
+}
+
+// readJSON reads a JSON file, allowing the comments and trailing commas of
+// VS Code's JSON files.
+func readJSON(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return stripJSONC(data), nil
+}
+
+// stripJSONC removes // and /* */ comments and trailing commas from data,
+// leaving strings untouched.
+func stripJSONC(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(data) {
+				i = len(data) - 1
+			}
+			out = append(out, data[start:i+1]...)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == ']' || c == '}':
+			// Drop a comma before the closing bracket.
+			j := len(out) - 1
+			for j >= 0 && strings.ContainsRune(" \t\r\n", rune(out[j])) {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// parseTOML parses the arrays of tables of a TOML document, such as the
+// [[snippets]] of pet, whose values are strings, arrays of strings or other
+// scalars kept as they are written.
+func parseTOML(data string) (map[string][]map[string]any, error) {
+	tables := make(map[string][]map[string]any)
+	var table map[string]any
+	p := tomlParser{data: data}
+	for {
+		p.skipSpace(true)
+		if p.done() {
+			return tables, nil
+		}
+		switch {
+		case strings.HasPrefix(p.rest(), "[["):
+			end := strings.Index(p.rest(), "]]")
+			if end < 0 {
+				return nil, p.errorf("unterminated table header")
+			}
+			name := strings.TrimSpace(p.rest()[2:end])
+			table = make(map[string]any)
+			tables[name] = append(tables[name], table)
+			p.pos += end + 2
+		case p.peek() == '[':
+			return nil, p.errorf("only arrays of tables are supported")
+		default:
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			if p.peek() != '=' {
+				return nil, p.errorf("expected = after %q", key)
+			}
+			p.pos++
+			p.skipSpace(false)
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if table != nil {
+				table[key] = value
+			}
+		}
+	}
+}
+
+// tomlParser reads TOML values from data, starting at pos.
+type tomlParser struct {
+	data string
+	pos  int
+}
+
+func (p *tomlParser) done() bool   { return p.pos >= len(p.data) }
+func (p *tomlParser) rest() string { return p.data[p.pos:] }
+
+func (p *tomlParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// errorf returns an error at the line of the current position.
+func (p *tomlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.data[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments, and line breaks if newlines is
+// set.
+func (p *tomlParser) skipSpace(newlines bool) {
+	for !p.done() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || ((c == '\n' || c == '\r') && newlines):
+			p.pos++
+		case c == '#':
+			for !p.done() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// key reads a bare or quoted key.
+func (p *tomlParser) key() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.string()
+	}
+	start := p.pos
+	for !p.done() && strings.IndexByte("=# \t\r\n", p.peek()) < 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected a key")
+	}
+	return p.data[start:p.pos], nil
+}
+
+// value reads a string, an array of strings or another scalar.
+func (p *tomlParser) value() (any, error) {
+	switch p.peek() {
+	case '"', '\'':
+		return p.string()
+	case '[':
+		p.pos++
+		values := []string{}
+		for {
+			p.skipSpace(true)
+			if p.peek() == ']' {
+				p.pos++
+				return values, nil
+			}
+			value, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			p.skipSpace(true)
+			if p.peek() == ',' {
+				p.pos++
+			}
+		}
+	}
+	start := p.pos
+	for !p.done() && strings.IndexByte("#\r\n", p.peek()) < 0 {
+		p.pos++
+	}
+	return strings.TrimSpace(p.data[start:p.pos]), nil
+}
+
+// string reads a basic or literal string, on one or multiple lines.
+func (p *tomlParser) string() (string, error) {
+	if c := p.peek(); c != '"' && c != '\'' {
+		return "", p.errorf("expected a string")
+	}
+	quote := p.rest()[:1]
+	delim := quote
+	if strings.HasPrefix(p.rest(), quote+quote+quote) {
+		delim = quote + quote + quote
+	}
+	p.pos += len(delim)
+	if len(delim) == 3 {
+		// A line break right after the opening delimiter is trimmed.
+		if strings.HasPrefix(p.rest(), "\r\n") {
+			p.pos += 2
+		} else if strings.HasPrefix(p.rest(), "\n") {
+			p.pos++
+		}
+	}
+
+	var b strings.Builder
+	for {
+		if p.done() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.rest(), delim) {
+			p.pos += len(delim)
+			return b.String(), nil
+		}
+		c := p.peek()
+		if c == '\n' && len(delim) == 1 {
+			return "", p.errorf("unterminated string")
+		}
+		if c != '\\' || quote == "'" {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+		if err := p.escape(&b, len(delim) == 3); err != nil {
+			return "", err
+		}
+	}
+}
+
+// escape reads the escape sequence of a basic string into b.
+func (p *tomlParser) escape(b *strings.Builder, multiline bool) error {
+	p.pos++
+	if p.done() {
+		return p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte('\x1b')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if len(p.rest()) < n {
+			return p.errorf("invalid escape sequence")
+		}
+		var r rune
+		if _, err := fmt.Sscanf(p.rest()[:n], "%x", &r); err != nil {
+			return p.errorf("invalid escape sequence")
+		}
+		b.WriteRune(r)
+		p.pos += n
+	case ' ', '\t', '\r', '\n':
+		if !multiline {
+			return p.errorf("invalid escape sequence")
+		}
+		// A backslash at the end of a line trims the following whitespace.
+		p.pos--
+		for !p.done() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+			p.pos++
+		}
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
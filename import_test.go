@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maaslalani/nap/store"
+)
+
+func TestImporters(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.json": `{
+	// Comments and trailing commas are allowed.
+	"Print": {"prefix": "pr", "body": ["fmt.Println(\"$1\")", "// done"],},
+}`,
+		"all.code-snippets": `{"Log": {"scope": "javascript,typescript", "body": "console.log($1)"}}`,
+		"gist.json":         `[{"created_at": "2022-01-02T03:04:05Z", "files": {"hello.py": {"filename": "hello.py", "content": "print('hi')"}}}]`,
+		"pet.toml": `# pet snippets
+[[snippets]]
+  description = "list \"files\""
+  command = 'ls -la'
+  tag = ["fs",
+    "shell"]
+  output = ""
+
+[[snippets]]
+  description = "multi"
+  command = """
+echo a \
+  b"""
+`,
+		"db.json": `{
+	"folders": [{"id": "f1", "name": "Work", "parentId": null}, {"id": "f2", "name": "Go", "parentId": "f1"}],
+	"tags": [{"id": "t1", "name": "http"}],
+	"snippets": [
+		{"name": "server", "folderId": "f2", "tagsIds": ["t1"], "isFavorites": true, "createdAt": 1650000000000,
+		 "content": [{"label": "main", "language": "golang", "value": "package main"}, {"label": "test", "language": "golang", "value": "package main_test"}]},
+		{"name": "gone", "folderId": "f1", "isDeleted": true, "content": [{"label": "Fragment 1", "language": "sh", "value": "rm"}]}
+	]
+}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Logf("could not write file: %v", err)
+			t.FailNow()
+		}
+	}
+
+	tt := []struct {
+		File   string
+		Format string
+		Want   []string
+	}{
+		{File: "go.json", Format: "vscode", Want: []string{`/Print.go [] false "fmt.Println(\"$1\")\n// done\n"`}},
+		{File: "all.code-snippets", Format: "vscode", Want: []string{`/Log.js [] false "console.log($1)\n"`}},
+		{File: "gist.json", Format: "gist", Want: []string{`/hello.py [] false "print('hi')"`}},
+		{File: "pet.toml", Format: "pet", Want: []string{`/list "files".sh [fs shell] false "ls -la\n"`, `/multi.sh [] false "echo a b\n"`}},
+		{File: "db.json", Format: "masscode", Want: []string{`Work/Go/server - main.go [http] true "package main"`, `Work/Go/server - test.go [http] true "package main_test"`}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Format, func(t *testing.T) {
+			path := filepath.Join(dir, tc.File)
+			format, err := detectFormat(path)
+			if err != nil || format != tc.Format {
+				t.Logf("format is incorrect: want %s but got %s (%v)", tc.Format, format, err)
+				t.FailNow()
+			}
+			imports, err := importers[format](path)
+			if err != nil {
+				t.Logf("could not import: %v", err)
+				t.FailNow()
+			}
+			var got []string
+			for _, imp := range imports {
+				got = append(got, fmt.Sprintf("%s %v %t %q", imp.Snippet, imp.Snippet.Tags, imp.Snippet.Favorite, imp.Content))
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.Want, "\n") {
+				t.Logf("imports are incorrect: want %q but got %q", tc.Want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestImportCLI(t *testing.T) {
+	tmp := tmpHome(t)
+	file := filepath.Join(t.TempDir(), "snippets.code-snippets")
+	if err := os.WriteFile(file, []byte(`{"a": {"scope": "go", "body": "a"}, "b": {"scope": "go", "body": "a"}}`), 0o644); err != nil {
+		t.Logf("could not write file: %v", err)
+		t.FailNow()
+	}
+
+	var b strings.Builder
+	if err := runImport(&b, store.New(tmp, "snippets.json"), []string{file, "--folder", "vscode"}); err != nil {
+		t.Logf("could not import: %v", err)
+		t.FailNow()
+	}
+	want := "Imported vscode/a.go\nSkipped vscode/b.go, same content as vscode/a.go\n1 imported, 1 skipped\n"
+	if b.String() != want {
+		t.Logf("output is incorrect: want %q but got %q", want, b.String())
+		t.FailNow()
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tt := []struct {
+		Name string
+		TOML string
+		Want string
+		Err  bool
+	}{
+		{
+			Name: "arrays of tables",
+			TOML: "title = \"ignored\"\n[[a]]\nx = 1\n[[b]]\ny = true # comment\n[[a]]\nx = 2\n",
+			Want: `map[a:[map[x:1] map[x:2]] b:[map[y:true]]]`,
+		},
+		{
+			Name: "quoted keys",
+			TOML: "[[a]]\n\"key with spaces\" = 'v'\n'literal' = \"w\"\n",
+			Want: `map[a:[map[key with spaces:v literal:w]]]`,
+		},
+		{
+			Name: "escapes",
+			TOML: `[[a]]` + "\n" + `s = "tab\tquote\" backslash\\ \u00e9\U0001F600 \e[0m"` + "\n",
+			Want: "map[a:[map[s:tab\tquote\" backslash\\ é😀 \x1b[0m]]]",
+		},
+		{
+			Name: "literal strings",
+			TOML: "[[a]]\ns = 'C:\\path\\n'\nm = '''\nline 1\n  line 2\\'''\n",
+			Want: "map[a:[map[m:line 1\n  line 2\\ s:C:\\path\\n]]]",
+		},
+		{
+			Name: "multi-line strings",
+			TOML: "[[a]]\ns = \"\"\"\r\none\n\"two\" \\\n   three\"\"\"\n",
+			Want: "map[a:[map[s:one\n\"two\" three]]]",
+		},
+		{
+			Name: "arrays",
+			TOML: "[[a]]\ntags = [ # comment\n  \"x\",\n  'y', # comment\n]\nempty = []\n",
+			Want: `map[a:[map[empty:[] tags:[x y]]]]`,
+		},
+		{
+			Name: "crlf",
+			TOML: "[[a]]\r\nx = \"1\"\r\ny = 2\r\n",
+			Want: `map[a:[map[x:1 y:2]]]`,
+		},
+		{Name: "unterminated string", TOML: "[[a]]\ns = \"abc\n", Err: true},
+		{Name: "unterminated multi-line string", TOML: "[[a]]\ns = \"\"\"abc\n", Err: true},
+		{Name: "invalid escape", TOML: "[[a]]\ns = \"\\q\"\n", Err: true},
+		{Name: "line ending backslash", TOML: "[[a]]\ns = \"a \\\n b\"\n", Err: true},
+		{Name: "short unicode escape", TOML: "[[a]]\ns = \"\\u12\"", Err: true},
+		{Name: "table", TOML: "[a]\nx = 1\n", Err: true},
+		{Name: "unterminated table header", TOML: "[[a\nx = 1\n", Err: true},
+		{Name: "missing equal sign", TOML: "[[a]]\nx 1\n", Err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			tables, err := parseTOML(tc.TOML)
+			if tc.Err != (err != nil) {
+				t.Logf("error is incorrect: want error %t but got %v", tc.Err, err)
+				t.FailNow()
+			}
+			if got := fmt.Sprint(tables); !tc.Err && got != tc.Want {
+				t.Logf("tables are incorrect: want %q but got %q", tc.Want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	tt := []struct {
+		Name  string
+		JSONC string
+		Want  string
+	}{
+		{Name: "line comments", JSONC: "{\n// comment\n\"a\": 1 // comment\n}", Want: "{\n\n\"a\": 1 \n}"},
+		{Name: "block comments", JSONC: "{/* a\n b */\"a\": /**/1}", Want: `{"a": 1}`},
+		{Name: "comments in strings", JSONC: `{"url": "http://x/*y*/", "s": "a // b"}`, Want: `{"url": "http://x/*y*/", "s": "a // b"}`},
+		{Name: "escaped quotes", JSONC: `{"s": "say \"//hi\"\\", "t": 1}`, Want: `{"s": "say \"//hi\"\\", "t": 1}`},
+		{Name: "trailing commas", JSONC: "{\"a\": [1, 2,], \"b\": {\"c\": 3,},\n}", Want: "{\"a\": [1, 2], \"b\": {\"c\": 3}\n}"},
+		{Name: "comma before comment", JSONC: "[1, // last\n]", Want: "[1 \n]"},
+		{Name: "brackets in strings", JSONC: `["a,]", "b,}"]`, Want: `["a,]", "b,}"]`},
+		{Name: "unterminated comment", JSONC: `{"a": 1 /* b`, Want: `{"a": 1 `},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := string(stripJSONC([]byte(tc.JSONC))); got != tc.Want {
+				t.Logf("stripped JSON is incorrect: want %q but got %q", tc.Want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
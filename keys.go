@@ -6,46 +6,72 @@ import "github.com/charmbracelet/bubbles/key"
 type KeyMap struct {
 	Quit            key.Binding
 	Search          key.Binding
+	SearchContent   key.Binding
+	FindSnippet     key.Binding
 	ToggleHelp      key.Binding
 	NewSnippet      key.Binding
 	MoveSnippetUp   key.Binding
 	MoveSnippetDown key.Binding
+	SortSnippets    key.Binding
+	GroupSnippets   key.Binding
 	DeleteSnippet   key.Binding
 	EditSnippet     key.Binding
+	EditInline      key.Binding
 	CopySnippet     key.Binding
 	PasteSnippet    key.Binding
 	SetFolder       key.Binding
 	RenameSnippet   key.Binding
 	TagSnippet      key.Binding
+	StarSnippet     key.Binding
+	ShowHistory     key.Binding
+	RestoreSnippet  key.Binding
 	SetLanguage     key.Binding
 	Confirm         key.Binding
 	Cancel          key.Binding
 	NextPane        key.Binding
 	PreviousPane    key.Binding
 	ChangeFolder    key.Binding
+	ToggleFolder    key.Binding
+	NewFolder       key.Binding
+	RenameFolder    key.Binding
+	MergeFolder     key.Binding
+	DeleteFolder    key.Binding
 }
 
 // DefaultKeyMap is the default key map for the application.
 var DefaultKeyMap = KeyMap{
 	Quit:            key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "exit")),
 	Search:          key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+	SearchContent:   key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "search contents")),
+	FindSnippet:     key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "find anywhere")),
 	ToggleHelp:      key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
 	NewSnippet:      key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new")),
 	MoveSnippetDown: key.NewBinding(key.WithKeys("J"), key.WithHelp("J", "move snippet down")),
 	MoveSnippetUp:   key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "move snippet up")),
+	SortSnippets:    key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "sort")),
+	GroupSnippets:   key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "group")),
 	DeleteSnippet:   key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete")),
 	EditSnippet:     key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+	EditInline:      key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "edit in place")),
 	CopySnippet:     key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
 	PasteSnippet:    key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "paste")),
 	RenameSnippet:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename snippet")),
-	SetFolder:       key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "rename folder")),
+	SetFolder:       key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "set folder")),
 	SetLanguage:     key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "set file type")),
-	TagSnippet:      key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "tag"), key.WithDisabled()),
+	TagSnippet:      key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "tag")),
+	StarSnippet:     key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "star")),
+	ShowHistory:     key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history")),
+	RestoreSnippet:  key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo delete")),
 	Confirm:         key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
 	Cancel:          key.NewBinding(key.WithKeys("N", "esc"), key.WithHelp("N", "cancel")),
 	NextPane:        key.NewBinding(key.WithKeys("tab", "right"), key.WithHelp("tab", "navigate")),
 	PreviousPane:    key.NewBinding(key.WithKeys("shift+tab", "left"), key.WithHelp("shift+tab", "navigate")),
 	ChangeFolder:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "change folder"), key.WithDisabled()),
+	ToggleFolder:    key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "collapse folder"), key.WithDisabled()),
+	NewFolder:       key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new folder"), key.WithDisabled()),
+	RenameFolder:    key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename folder"), key.WithDisabled()),
+	MergeFolder:     key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "merge folder"), key.WithDisabled()),
+	DeleteFolder:    key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete folder"), key.WithDisabled()),
 }
 
 // ShortHelp returns a quick help menu.
@@ -64,10 +90,38 @@ func (k KeyMap) ShortHelp() []key.Binding {
 // FullHelp returns all help options in a more detailed view.
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.NewSnippet, k.EditSnippet, k.PasteSnippet, k.CopySnippet, k.DeleteSnippet},
-		{k.MoveSnippetDown, k.MoveSnippetUp},
-		{k.RenameSnippet, k.SetFolder, k.TagSnippet, k.SetLanguage},
+		{k.NewSnippet, k.EditSnippet, k.EditInline, k.PasteSnippet, k.CopySnippet, k.DeleteSnippet, k.RestoreSnippet, k.ShowHistory},
+		{k.MoveSnippetDown, k.MoveSnippetUp, k.SortSnippets, k.GroupSnippets},
+		{k.RenameSnippet, k.SetFolder, k.TagSnippet, k.StarSnippet, k.SetLanguage},
 		{k.NextPane, k.PreviousPane},
-		{k.Search, k.ToggleHelp, k.Quit},
+		{k.NewFolder, k.RenameFolder, k.MergeFolder, k.DeleteFolder, k.ToggleFolder},
+		{k.Search, k.SearchContent, k.FindSnippet, k.ToggleHelp, k.Quit},
 	}
 }
+
+// EditorKeyMap is the mappings of the actions of the built-in editor to key
+// bindings. Other keys edit the content.
+type EditorKeyMap struct {
+	Save  key.Binding
+	Undo  key.Binding
+	Redo  key.Binding
+	Close key.Binding
+}
+
+// DefaultEditorKeyMap is the default key map of the built-in editor.
+var DefaultEditorKeyMap = EditorKeyMap{
+	Save:  key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save")),
+	Undo:  key.NewBinding(key.WithKeys("ctrl+z"), key.WithHelp("ctrl+z", "undo")),
+	Redo:  key.NewBinding(key.WithKeys("ctrl+y"), key.WithHelp("ctrl+y", "redo")),
+	Close: key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "close")),
+}
+
+// ShortHelp returns a quick help menu.
+func (k EditorKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Save, k.Undo, k.Redo, k.Close}
+}
+
+// FullHelp returns all help options in a more detailed view.
+func (k EditorKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
@@ -10,26 +10,30 @@ import (
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dustin/go-humanize"
+	"github.com/maaslalani/nap/store"
 )
 
-// FilterValue is the snippet filter value that can be used when searching.
-func (s Snippet) FilterValue() string {
-	return s.Folder + "/" + s.Name + "\n" + "+" + strings.Join(s.Tags, "+") + "\n" + s.Language
-}
-
 // snippetDelegate represents the snippet list item.
 type snippetDelegate struct {
 	styles SnippetsBaseStyle
 	state  state
+	order  listOrder
 }
 
-// Height is the number of lines the snippet list item takes up.
+// Height is the number of lines the snippet list item takes up. Grouped
+// items take up the spacing line, to show the header of their group.
 func (d snippetDelegate) Height() int {
+	if d.order.group != noGroup {
+		return 3
+	}
 	return 2
 }
 
 // Spacing is the number of lines to insert between list items.
 func (d snippetDelegate) Spacing() int {
+	if d.order.group != noGroup {
+		return 0
+	}
 	return 1
 }
 
@@ -37,21 +41,32 @@ func (d snippetDelegate) Spacing() int {
 // We use this to update the snippet code view.
 func (d snippetDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 	return func() tea.Msg {
-		if m.SelectedItem() == nil {
-			return nil
+		switch item := m.SelectedItem().(type) {
+		case store.Snippet:
+			return updateContentMsg(item)
+		case trashItem:
+			return updateContentMsg(item.Snippet)
 		}
-		return updateContentMsg(m.SelectedItem().(Snippet))
+		return nil
 	}
 }
 
 // Render renders the list item for the snippet which includes the title,
-// folder, and date.
+// folder, and date, which is the date the snippets are sorted by or the
+// modification date, preceded by the header of its group if it is the first
+// of it.
 func (d snippetDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
 	if item == nil {
 		return
 	}
-	s, ok := item.(Snippet)
-	if !ok {
+	var s store.Snippet
+	var deleted time.Time
+	switch item := item.(type) {
+	case store.Snippet:
+		s = item
+	case trashItem:
+		s, deleted = item.Snippet, item.Deleted
+	default:
 		return
 	}
 
@@ -65,13 +80,73 @@ func (d snippetDelegate) Render(w io.Writer, m list.Model, index int, item list.
 		subtitleStyle = d.styles.DeletedSubtitle
 	}
 
+	subtitle := s.Folder + " • " + d.date(s)
+	if !deleted.IsZero() {
+		subtitle = s.Folder + " • deleted " + humanizeTime(deleted)
+	} else if len(s.Tags) > 0 {
+		subtitle += " • " + tagsString(s.Tags)
+	}
+	subtitle = truncate.Truncate(subtitle, 30, "...", truncate.PositionEnd)
+
+	if d.order.group != noGroup {
+		if header, ok := d.header(m, index, s); ok {
+			fmt.Fprint(w, "  "+d.styles.GroupHeader.Render(truncate.Truncate(header, 30, "...", truncate.PositionEnd)))
+		}
+		fmt.Fprintln(w)
+	}
+
+	title := truncate.Truncate(s.Name, 30, "...", truncate.PositionEnd)
+	if s.Favorite {
+		title = starGlyph + " " + truncate.Truncate(s.Name, 28, "...", truncate.PositionEnd)
+	}
+
 	if index == m.Index() {
-		fmt.Fprintln(w, "  "+titleStyle.Render(truncate.Truncate(s.Name, 30, "...", truncate.PositionEnd)))
-		fmt.Fprint(w, "  "+subtitleStyle.Render(s.Folder+" • "+humanizeTime(s.Date)))
+		fmt.Fprintln(w, "  "+titleStyle.Render(title))
+		fmt.Fprint(w, "  "+subtitleStyle.Render(subtitle))
 		return
 	}
-	fmt.Fprintln(w, "  "+d.styles.UnselectedTitle.Render(truncate.Truncate(s.Name, 30, "...", truncate.PositionEnd)))
-	fmt.Fprint(w, "  "+d.styles.UnselectedSubtitle.Render(s.Folder+" • "+humanizeTime(s.Date)))
+	fmt.Fprintln(w, "  "+d.styles.UnselectedTitle.Render(title))
+	fmt.Fprint(w, "  "+d.styles.UnselectedSubtitle.Render(subtitle))
+}
+
+// date returns the date of the snippet shown in its subtitle, or how many
+// times it was used when sorted by it.
+func (d snippetDelegate) date(s store.Snippet) string {
+	switch d.order.sort {
+	case createdSort:
+		return "created " + humanizeTime(s.Created)
+	case usedSort:
+		if s.LastUsed.IsZero() {
+			return "never used"
+		}
+		return "used " + humanizeTime(s.LastUsed)
+	case usesSort:
+		switch s.Uses {
+		case 0:
+			return "never used"
+		case 1:
+			return "used once"
+		}
+		return fmt.Sprintf("used %d times", s.Uses)
+	}
+	return humanizeTime(s.Modified)
+}
+
+// header returns the header of the group of the snippet at index, if it is
+// the first of its group in the list. Filtered lists are ranked rather than
+// grouped, so they have no headers.
+func (d snippetDelegate) header(m list.Model, index int, s store.Snippet) (string, bool) {
+	if m.FilterState() != list.Unfiltered {
+		return "", false
+	}
+	group := d.order.group.of(s)
+	if index > 0 {
+		items := m.VisibleItems()
+		if prev, ok := items[index-1].(store.Snippet); ok && d.order.group.of(prev) == group {
+			return "", false
+		}
+	}
+	return d.order.group.header(group), true
 }
 
 // Folder represents a group of snippets in a directory.
@@ -82,8 +157,47 @@ func (f Folder) FilterValue() string {
 	return string(f)
 }
 
+// view is a virtual folder which gathers the snippets of every folder that
+// match some criteria, such as having a tag, or which lists its own items,
+// in the given order until another one is picked.
+type view struct {
+	name  string
+	match func(store.Snippet) bool
+	items func() []list.Item
+	order listOrder
+}
+
+// FilterValue is the searchable value for the view.
+func (v view) FilterValue() string {
+	return v.name
+}
+
+// starGlyph marks favorite snippets.
+const starGlyph = "★"
+
+// favoritesView is a view of all the favorite snippets.
+var favoritesView = view{
+	name: starGlyph + " Favorites",
+	match: func(s store.Snippet) bool {
+		return s.Favorite
+	},
+}
+
+// tagView returns a view of all the snippets tagged with tag.
+func tagView(tag string) view {
+	return view{
+		name: "#" + tag,
+		match: func(s store.Snippet) bool {
+			return s.HasTag(tag)
+		},
+	}
+}
+
 // folderDelegate represents a folder list item.
-type folderDelegate struct{ styles FoldersBaseStyle }
+type folderDelegate struct {
+	styles    FoldersBaseStyle
+	collapsed map[Folder]bool
+}
 
 // Height is the number of lines the folder list item takes up.
 func (d folderDelegate) Height() int {
@@ -103,16 +217,38 @@ func (d folderDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 
 // Render renders a folder list item.
 func (d folderDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
-	f, ok := item.(Folder)
-	if !ok {
+	var name string
+	switch f := item.(type) {
+	case Folder:
+		name = d.folderName(f, m.Items(), index)
+	case view:
+		name = f.name
+	default:
 		return
 	}
 	fmt.Fprint(w, "  ")
 	if index == m.Index() {
-		fmt.Fprint(w, d.styles.Selected.Render("→ "+string(f)))
+		fmt.Fprint(w, d.styles.Selected.Render("→ "+name))
 		return
 	}
-	fmt.Fprint(w, d.styles.Unselected.Render("  "+string(f)))
+	fmt.Fprint(w, d.styles.Unselected.Render("  "+name))
+}
+
+// folderName returns the name of the folder indented by its depth in the
+// tree, marked as collapsed or expanded if it has nested folders.
+func (d folderDelegate) folderName(f Folder, items []list.Item, index int) string {
+	var glyph string
+	if d.collapsed[f] {
+		glyph = "▸ "
+	} else if index+1 < len(items) {
+		if next, ok := items[index+1].(Folder); ok && f.contains(next) {
+			glyph = "▾ "
+		}
+	}
+	if glyph == "" && f.depth() > 0 {
+		glyph = "  "
+	}
+	return strings.Repeat("  ", f.depth()) + glyph + f.name()
 }
 
 const (
@@ -2,13 +2,11 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"io/fs"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -20,8 +18,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/sahilm/fuzzy"
-	"golang.org/x/exp/maps"
+	"github.com/maaslalani/nap/store"
 	"golang.org/x/exp/slices"
 )
 
@@ -31,58 +28,402 @@ Nap is a code snippet manager for your terminal.
 https://github.com/maaslalani/nap
 
 Usage:
-  nap           - for interactive mode
-  nap list      - list all snippets
-  nap <snippet> - print snippet to stdout
+  nap               - for interactive mode
+  nap list          - list all snippets
+  nap list <folder> - list the snippets of a folder and its sub-folders
+  nap <snippet>     - print snippet to stdout
+  nap <id>          - print the snippet with the ID to stdout
 
 Create:
   nap < main.go                 - save snippet from stdin
-  nap example/main.go < main.go - save snippet with name`)
+  nap example/main.go < main.go - save snippet with name
+  nap add main.go [example/]    - save snippet from a file
+  nap import <path>...          - import a directory tree or a snippets file
+  nap import --dry-run <path>   - print what would be imported
+
+Export (filtered by folder, --tag or --favorites):
+  nap export -o backup.tar.gz [folder]... - export an archive to import elsewhere
+  nap export -o snippets.zip    - export a zip archive
+  nap export > snippets.md      - export a Markdown document
+  nap export -o nap.code-snippets - export VS Code snippets
+
+Manage (dest is folder/, name.lang or folder/name.lang):
+  nap show <snippet>            - print snippet to stdout
+  nap show --meta <snippet>     - print the metadata of a snippet
+  nap edit <snippet>            - open snippet in $VISUAL or $EDITOR
+  nap edit <snippet>:<line>     - open snippet at a line, as printed by search
+  nap mv <snippet> <dest>       - rename or move a snippet
+  nap cp <snippet> <dest>       - duplicate a snippet
+  nap rm <snippet>...           - move snippets to the trash
+  --force                       - replace a snippet at the destination
+
+Lookup (for printing, history and the commands above):
+  --exact                       - only match IDs and full names
+  --first                       - pick the best match of an ambiguous search
+  Exits with 2 if no snippet matches and 3 if several match equally well,
+  and with 64 for invalid flags or arguments.
+
+Output (for list and show):
+  --format json|tsv             - print snippets as JSON or tab separated values
+  --format '{{.Folder}}/{{.Name}}' - print snippets with a Go template
+  --sort modified|created|used  - sort newest first (list)
+  --sort uses                   - sort most used first (list)
+  --sort name|folder            - sort alphabetically (list)
+  --reverse                     - reverse the order (list)
+  --limit N                     - print at most N snippets (list)
+
+Search:
+  nap search <pattern>      - search the contents of all snippets
+  nap search -F <text>      - search for a fixed string
+  nap search -i <pattern>   - search ignoring case
+
+History:
+  nap history <snippet>           - list the revisions of a snippet
+  nap history <snippet> --rev N   - show the changes of a revision
+  nap restore <snippet> --rev N   - restore a snippet to a revision
+
+Sync (with git: true in the configuration):
+  nap sync                      - pull, rebase and push the snippets
+
+Folders:
+  nap folder list               - list all folders
+  nap folder create <folder>    - create an empty folder
+  nap folder rename <from> <to> - rename a folder and its snippets
+  nap folder merge <from> <to>  - move the snippets of a folder into another
+  nap folder delete <folder>    - move the snippets of a folder to the trash
+
+Trash:
+  nap trash list                - list deleted snippets
+  nap trash restore <snippet>   - restore a deleted snippet
+  nap trash empty               - permanently remove deleted snippets
+
+Templates (snippets tagged "template"):
+  nap --var name=value <snippet> - fill in ${name} placeholders
+  nap --raw <snippet>            - print without filling in placeholders
+
+Tags and favorites:
+  nap --tag go < main.go    - save snippet with a tag
+  nap --favorites < main.go - save snippet as a favorite
+  nap list --tag go         - list snippets with a tag
+  nap list --favorites      - list favorite snippets
+  nap --tag go <snippet>    - print snippet with a tag to stdout
+
+Usage (counted on print, copy and edit):
+  nap stats                 - most and least used, and never used snippets
+  nap stats --limit N       - print N most and least used snippets
+  nap stats --tag go        - statistics of the snippets with a tag`)
 )
 
 func main() {
-	runCLI(os.Args[1:])
+	os.Exit(runCLI(os.Args[1:]))
 }
 
-func runCLI(args []string) {
+func runCLI(args []string) int {
 	config := readConfig()
-	snippets := readSnippets(config)
-	snippets = migrateSnippets(config, snippets)
-	snippets = scanSnippets(config, snippets)
+	st := store.New(config.Home, config.File)
+	if config.Git {
+		if err := st.EnableGit(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	snippets, err := st.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if config.TrashDays > 0 {
+		_, _ = st.EmptyTrash(time.Now().AddDate(0, 0, -config.TrashDays))
+	}
+
+	var filter snippetFilter
+	var lookup snippetLookup
+	var raw bool
+	vars := varsFlag{}
+	flags := newFlagSet("nap")
+	filter.register(flags)
+	lookup.register(flags)
+	flags.Var(vars, "var", "value of a template placeholder as name=value")
+	flags.BoolVar(&raw, "raw", false, "print templates without filling in placeholders")
+	if err := parseFlags(flags, args); err != nil {
+		return fail(err)
+	}
+	args = flags.Args()
 
 	stdin := readStdin()
 	if stdin != "" {
-		saveSnippet(stdin, args, config, snippets)
-		return
+		if err := saveSnippet(stdin, args, filter, st); err != nil {
+			return fail(err)
+		}
+		return 0
 	}
 
 	if len(args) > 0 {
 		switch args[0] {
 		case "list":
-			listSnippets(snippets)
-		case "-h", "--help":
-			fmt.Println(helpText)
+			var options outputOptions
+			flags := newFlagSet("list")
+			filter.register(flags)
+			options.register(flags, true)
+			folders, err := parseArgs(flags, args[1:])
+			if err != nil {
+				return fail(err)
+			}
+			if err := printSnippets(os.Stdout, st, inFolders(filter.apply(snippets), folders), options); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		case "search":
+			var fixed, ignoreCase bool
+			flags := newFlagSet("search")
+			filter.register(flags)
+			flags.BoolVar(&fixed, "F", false, "interpret the pattern as a fixed string")
+			flags.BoolVar(&fixed, "fixed-strings", false, "interpret the pattern as a fixed string")
+			flags.BoolVar(&ignoreCase, "i", false, "ignore case distinctions")
+			flags.BoolVar(&ignoreCase, "ignore-case", false, "ignore case distinctions")
+			if err := parseFlags(flags, args[1:]); err != nil {
+				return fail(err)
+			}
+			if flags.NArg() == 0 {
+				return fail(errUsage("search [-F] [-i] <pattern>"))
+			}
+			re, err := compilePattern(strings.Join(flags.Args(), " "), fixed, ignoreCase)
+			if err != nil {
+				return fail(err)
+			}
+			matches, err := st.Search(filter.apply(snippets), re)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			printMatches(os.Stdout, matches, isatty.IsTerminal(os.Stdout.Fd()), config)
+		case "sync":
+			if !config.Git {
+				fmt.Fprintln(os.Stderr, "git mode is disabled, set git: true in the configuration to sync")
+				return 1
+			}
+			if err := st.Sync(config.GitRemote); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		case "folder":
+			if err := runFolder(os.Stdout, st, args[1:]); err != nil {
+				return fail(err)
+			}
+		case "import":
+			if err := runImport(os.Stdout, st, args[1:]); err != nil {
+				return fail(err)
+			}
+		case "trash":
+			if err := runTrash(os.Stdout, st, args[1:]); err != nil {
+				return fail(err)
+			}
+		case "add", "edit", "rm", "mv", "cp", "show", "export", "stats":
+			cmd := commands[args[0]]
+			if err := cmd(os.Stdout, st, config, filter.apply(snippets), args[1:]); err != nil {
+				return fail(err)
+			}
+		case "history", "restore":
+			var rev int
+			flags := newFlagSet(args[0])
+			filter.register(flags)
+			lookup.register(flags)
+			flags.IntVar(&rev, "rev", 0, "revision to show or restore")
+			names, err := parseArgs(flags, args[1:])
+			if err != nil {
+				return fail(err)
+			}
+			if len(names) == 0 {
+				return fail(errUsage(args[0] + " <snippet> [--rev N]"))
+			}
+			snippet, err := lookup.resolve(strings.Join(names, " "), filter.apply(snippets))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitCode(err)
+			}
+			if args[0] == "restore" {
+				err = restoreSnippet(st, snippet, rev)
+			} else {
+				err = printHistory(os.Stdout, st, snippet, rev, isatty.IsTerminal(os.Stdout.Fd()), config)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
 		default:
-			snippet := findSnippet(args[0], snippets)
-			fmt.Print(snippet.Content(isatty.IsTerminal(os.Stdout.Fd())))
+			snippet, err := lookup.resolve(args[0], filter.apply(snippets))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitCode(err)
+			}
+			if err := printSnippet(os.Stdout, snippet, st, config, vars, raw); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+		return 0
+	}
+
+	if filter.active() {
+		if err := printSnippets(os.Stdout, st, filter.apply(snippets), outputOptions{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
-		return
+		return 0
 	}
 
-	err := runInteractiveMode(config, snippets)
+	err = runInteractiveMode(config, st, snippets)
 	if err != nil {
-		fmt.Println("Alas, there's been an error", err)
+		fmt.Fprintln(os.Stderr, "Alas, there's been an error", err)
+		return 1
+	}
+	return 0
+}
+
+// fail prints the error of a command to stderr, unless it is flag.ErrHelp
+// once the help was printed, and returns its exit code.
+func fail(err error) int {
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return exitCode(err)
+}
+
+// printSnippet prints the content of the snippet to w, highlighted if stdout
+// is a terminal. The placeholders of templates are filled in with
+// vars, prompting for missing values if stdin is a terminal, unless raw is
+// set.
+func printSnippet(w io.Writer, snippet store.Snippet, st *store.Store, config Config, vars map[string]string, raw bool) error {
+	b, _ := st.Content(snippet)
+	content := string(b)
+	if isTemplate(snippet) && !raw {
+		if isatty.IsTerminal(os.Stdin.Fd()) {
+			if err := promptPlaceholders(os.Stderr, os.Stdin, placeholders(content), vars); err != nil {
+				return err
+			}
+		}
+		var err error
+		content, err = renderTemplate(content, vars)
+		if err != nil {
+			return err
+		}
+	}
+
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		fmt.Fprint(w, highlightContent(content, snippet.Language, config.Theme))
+	} else {
+		fmt.Fprint(w, content)
 	}
+	_, _ = st.Used(snippet)
+	return nil
+}
+
+// newFlagSet returns a flag set for a (sub)command which reports errors
+// through parseFlags rather than printing and exiting on its own.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parseFlags parses args into flags. The help text is printed for -h or
+// --help, which returns flag.ErrHelp, and invalid flags return a usageError
+// followed by the help text.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Println(helpText)
+		return err
+	}
+	if err != nil {
+		return usageError(err.Error() + "\n\n" + helpText)
+	}
+	return nil
+}
+
+// parseArgs is like parseFlags but also accepts flags after the positional
+// arguments, which it returns.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := parseFlags(flags, args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// snippetFilter selects snippets based on command line flags.
+type snippetFilter struct {
+	tags      tagsFlag
+	favorites bool
+}
+
+// register defines the filter flags on the flag set.
+func (f *snippetFilter) register(flags *flag.FlagSet) {
+	flags.Var(&f.tags, "tag", "only consider snippets with the given tag")
+	flags.BoolVar(&f.favorites, "favorites", f.favorites, "only consider favorite snippets")
+}
+
+// active returns whether any filter flag was given.
+func (f snippetFilter) active() bool {
+	return len(f.tags) > 0 || f.favorites
+}
+
+// apply returns the snippets selected by the filter.
+func (f snippetFilter) apply(snippets []store.Snippet) []store.Snippet {
+	if !f.active() {
+		return snippets
+	}
+	var filtered []store.Snippet
+	for _, snippet := range snippets {
+		if f.matches(snippet) {
+			filtered = append(filtered, snippet)
+		}
+	}
+	return filtered
+}
+
+// matches returns whether the snippet has all of the tags and is a favorite
+// if only favorites are selected.
+func (f snippetFilter) matches(snippet store.Snippet) bool {
+	if f.favorites && !snippet.Favorite {
+		return false
+	}
+	for _, tag := range f.tags {
+		if !snippet.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// tagsFlag is a flag which can be repeated or given a comma separated list to
+// collect multiple tags.
+type tagsFlag []string
+
+// String returns the tags joined by commas.
+func (t *tagsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+// Set adds the tags in value to the flag.
+func (t *tagsFlag) Set(value string) error {
+	*t = append(*t, parseTags(value)...)
+	return nil
 }
 
 // parseName returns a folder, name, and language for the given name.
 // this is useful for parsing file names when passed as command line arguments.
+// Everything up to the last slash is the folder, which may be nested.
 //
 // Example:
 //
-//	Notes/Hello.go -> (Notes, Hello, go)
-//	Hello.go       -> (Misc, Hello, go)
-//	Notes/Hello    -> (Notes, Hello, go)
+//	Notes/Hello.go    -> (Notes, Hello, go)
+//	Hello.go          -> (Misc, Hello, go)
+//	Notes/Hello       -> (Notes, Hello, go)
+//	work/go/http.go   -> (work/go, http, go)
 func parseName(s string) (string, string, string) {
 	var (
 		folder    = defaultSnippetFolder
@@ -91,15 +432,14 @@ func parseName(s string) (string, string, string) {
 		remaining string
 	)
 
-	tokens := strings.Split(s, "/")
-	if len(tokens) > 1 {
-		folder = tokens[0]
-		remaining = tokens[1]
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		folder = strings.Trim(s[:i], "/")
+		remaining = s[i+1:]
 	} else {
-		remaining = tokens[0]
+		remaining = s
 	}
 
-	tokens = strings.Split(remaining, ".")
+	tokens := strings.Split(remaining, ".")
 	if len(tokens) > 1 {
 		name = tokens[0]
 		language = tokens[1]
@@ -138,138 +478,7 @@ func readStdin() string {
 	return b.String()
 }
 
-// readSnippets returns all the snippets read from the snippets.json file.
-func readSnippets(config Config) []Snippet {
-	var snippets []Snippet
-	file := filepath.Join(config.Home, config.File)
-	dir, err := os.ReadFile(file)
-	if err != nil {
-		// File does not exist, create one.
-		err := os.MkdirAll(config.Home, os.ModePerm)
-		if err != nil {
-			fmt.Printf("Unable to create directory %s, %+v", config.Home, err)
-		}
-		f, err := os.Create(file)
-		if err != nil {
-			fmt.Printf("Unable to create file %s, %+v", file, err)
-		}
-		defer f.Close()
-		dir = []byte("[]")
-		_, _ = f.Write(dir)
-	}
-	err = json.Unmarshal(dir, &snippets)
-	if err != nil {
-		fmt.Printf("Unable to unmarshal %s file, %+v\n", file, err)
-		return snippets
-	}
-	return snippets
-}
-
-// migrateSnippets migrates any legacy snippet <dir>-<file> format to the new <dir>/<file> format
-func migrateSnippets(config Config, snippets []Snippet) []Snippet {
-	var migrated bool
-	for idx, snippet := range snippets {
-		legacyPath := filepath.Join(config.Home, snippet.LegacyPath())
-		if _, err := os.Stat(legacyPath); err != nil {
-			if !errors.Is(err, fs.ErrNotExist) {
-				fmt.Printf("could not access %q: %v\n", legacyPath, err)
-			}
-			continue
-		}
-		file := strings.TrimPrefix(snippet.LegacyPath(), fmt.Sprintf("%s-", snippet.Folder))
-		newDir := filepath.Join(config.Home, snippet.Folder)
-		newPath := filepath.Join(newDir, file)
-		if err := os.MkdirAll(newDir, os.ModePerm); err != nil {
-			fmt.Printf("could not create %q: %v\n", newDir, err)
-			continue
-		}
-		if err := os.Rename(legacyPath, newPath); err != nil {
-			fmt.Printf("could not move %q to %q: %v\n", legacyPath, newPath, err)
-		}
-		migrated = true
-		snippet.File = file
-		snippets[idx] = snippet
-	}
-	if migrated {
-		writeSnippets(config, snippets)
-	}
-	return snippets
-}
-
-// scanSnippets scans for any new/removed snippets and adds them to snippets.json
-func scanSnippets(config Config, snippets []Snippet) []Snippet {
-	var modified bool
-	snippetExists := func(path string) bool {
-		for _, snippet := range snippets {
-			if path == snippet.Path() {
-				return true
-			}
-		}
-		return false
-	}
-
-	homeEntries, err := os.ReadDir(config.Home)
-	if err != nil {
-		fmt.Printf("could not scan config home: %v\n", err)
-		return snippets
-	}
-
-	for _, homeEntry := range homeEntries {
-		if !homeEntry.IsDir() {
-			continue
-		}
-		if strings.HasPrefix(homeEntry.Name(), ".") {
-			continue
-		}
-
-		folderPath := filepath.Join(config.Home, homeEntry.Name())
-		folderEntries, err := os.ReadDir(folderPath)
-		if err != nil {
-			fmt.Printf("could not scan %q: %v\n", folderPath, err)
-			continue
-		}
-
-		for _, folderEntry := range folderEntries {
-			if folderEntry.IsDir() {
-				continue
-			}
-
-			snippetPath := filepath.Join(homeEntry.Name(), folderEntry.Name())
-			if !snippetExists(snippetPath) {
-				name := folderEntry.Name()
-				ext := filepath.Ext(name)
-				snippets = append(snippets, Snippet{
-					Folder:   homeEntry.Name(),
-					Date:     time.Now(),
-					Name:     strings.TrimSuffix(name, ext),
-					File:     name,
-					Language: strings.TrimPrefix(ext, "."),
-					Tags:     make([]string, 0),
-				})
-				modified = true
-			}
-		}
-	}
-
-	var idx int
-	for _, snippet := range snippets {
-		snippetPath := filepath.Join(config.Home, snippet.Path())
-		if _, err := os.Stat(snippetPath); !errors.Is(err, fs.ErrNotExist) {
-			snippets[idx] = snippet
-			idx++
-			modified = true
-		}
-	}
-	snippets = snippets[:idx]
-
-	if modified {
-		writeSnippets(config, snippets)
-	}
-
-	return snippets
-}
-
-func saveSnippet(content string, args []string, config Config, snippets []Snippet) {
+func saveSnippet(content string, args []string, filter snippetFilter, st *store.Store) error {
 	// Save snippet to location
 	name := defaultSnippetName
 	if len(args) > 0 {
@@ -277,81 +486,83 @@ func saveSnippet(content string, args []string, config Config, snippets []Snippe
 	}
 
 	folder, name, language := parseName(name)
-	file := fmt.Sprintf("%s.%s", name, language)
-	filePath := filepath.Join(config.Home, folder, file)
-	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-		fmt.Println("unable to create folder")
-		return
-	}
-	err := os.WriteFile(filePath, []byte(content), 0o644)
-	if err != nil {
-		fmt.Println("unable to create snippet")
-		return
-	}
-
-	// Add snippet metadata
-	snippet := Snippet{
+	snippet := store.Snippet{
 		Folder:   folder,
-		Date:     time.Now(),
 		Name:     name,
-		File:     file,
+		File:     store.FileName(name, language),
 		Language: language,
+		Tags:     filter.tags,
+		Favorite: filter.favorites,
 	}
-
-	snippets = append([]Snippet{snippet}, snippets...)
-	writeSnippets(config, snippets)
+	if snippet.Tags == nil {
+		snippet.Tags = []string{}
+	}
+	return st.Create(snippet, []byte(content))
 }
 
-func writeSnippets(config Config, snippets []Snippet) {
-	b, err := json.Marshal(snippets)
-	if err != nil {
-		fmt.Println("Could not marshal latest snippet data.", err)
-		return
+// inFolders returns the snippets in any of the folders or their nested
+// folders, or all of the snippets if no folder is given.
+func inFolders(snippets []store.Snippet, folders []string) []store.Snippet {
+	if len(folders) == 0 {
+		return snippets
 	}
-	err = os.WriteFile(filepath.Join(config.Home, config.File), b, os.ModePerm)
-	if err != nil {
-		fmt.Println("Could not save snippets file.", err)
+	var found []store.Snippet
+	for _, snippet := range snippets {
+		for _, folder := range folders {
+			if snippet.InFolder(folder) {
+				found = append(found, snippet)
+				break
+			}
+		}
 	}
+	return found
 }
 
-func listSnippets(snippets []Snippet) {
-	for _, snippet := range snippets {
-		fmt.Println(snippet)
+func runInteractiveMode(config Config, st *store.Store, snippets []store.Snippet) error {
+	m := newModel(config, st, snippets, readState())
+
+	// Changes made by other programs while nap is open are merged in live.
+	if watcher, err := st.Watch(watchInterval); err == nil {
+		m.watcher = watcher
+		defer watcher.Close()
 	}
-}
 
-func findSnippet(search string, snippets []Snippet) Snippet {
-	matches := fuzzy.FindFrom(search, Snippets{snippets})
-	if len(matches) > 0 {
-		return snippets[matches[0].Index]
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	model, err := p.Run()
+	if err != nil {
+		return err
+	}
+	fm, ok := model.(*Model)
+	if !ok {
+		return err
 	}
-	return Snippet{}
+	return st.SaveChanges(fm.base, fm.allSnippets())
 }
 
-func runInteractiveMode(config Config, snippets []Snippet) error {
+// newModel returns the model of the interactive mode showing the snippets,
+// with the folders and the snippet selected in the last session.
+func newModel(config Config, st *store.Store, snippets []store.Snippet, state State) *Model {
+	// base is what the snippets looked like before the session, so that
+	// changes made by others in the meantime are not overwritten on exit.
+	base := slices.Clone(snippets)
 	if len(snippets) == 0 {
 		// welcome to nap!
 		snippets = append(snippets, defaultSnippet)
 	}
-	state := readState()
 
 	folders := make(map[Folder][]list.Item)
 	for _, snippet := range snippets {
 		folders[Folder(snippet.Folder)] = append(folders[Folder(snippet.Folder)], list.Item(snippet))
 	}
 
+	collapsed := make(map[Folder]bool)
+	for _, folder := range state.CollapsedFolders {
+		collapsed[Folder(folder)] = true
+	}
+
 	defaultStyles := DefaultStyles(config)
 
-	var folderItems []list.Item
-	foldersSlice := maps.Keys(folders)
-	slices.Sort(foldersSlice)
-	for _, folder := range foldersSlice {
-		folderItems = append(folderItems, list.Item(folder))
-	}
-	if len(folderItems) <= 0 {
-		folderItems = append(folderItems, list.Item(Folder(defaultSnippetFolder)))
-	}
-	folderList := list.New(folderItems, folderDelegate{defaultStyles.Folders.Blurred}, 0, 0)
+	folderList := list.New(nil, folderDelegate{defaultStyles.Folders.Blurred, collapsed}, 0, 0)
 	folderList.Title = "Folders"
 
 	folderList.SetShowHelp(false)
@@ -361,32 +572,23 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 	folderList.Styles.NoItems = lipgloss.NewStyle().Margin(0, 2).Foreground(lipgloss.Color(config.GrayColor))
 	folderList.SetStatusBarItemName("folder", "folders")
 
-	for idx, folder := range foldersSlice {
-		if string(folder) == state.CurrentFolder {
-			folderList.Select(idx)
-			break
+	content := viewport.New(80, 0)
+
+	// Empty folders are listed along with the folders of the snippets.
+	diskFolders, _ := st.Folders()
+	for _, folder := range diskFolders {
+		if _, ok := folders[Folder(folder)]; !ok {
+			folders[Folder(folder)] = []list.Item{}
 		}
 	}
 
-	content := viewport.New(80, 0)
-
 	lists := map[Folder]*list.Model{}
-
-	currentFolder := folderList.SelectedItem().(Folder)
 	for folder, items := range folders {
-		snippetList := newList(items, 20, defaultStyles.Snippets.Focused)
-		if folder == currentFolder {
-			for idx, item := range snippetList.Items() {
-				if s, ok := item.(Snippet); ok && s.File == state.CurrentSnippet {
-					snippetList.Select(idx)
-					break
-				}
-			}
-		}
-		lists[folder] = snippetList
+		lists[folder] = newList(items, 20, defaultStyles.Snippets.Focused)
 	}
 
 	m := &Model{
+		base:         base,
 		Lists:        lists,
 		Folders:      folderList,
 		Code:         content,
@@ -394,41 +596,59 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 		ListStyle:    defaultStyles.Snippets.Focused,
 		FoldersStyle: defaultStyles.Folders.Blurred,
 		keys:         DefaultKeyMap,
+		editorKeys:   DefaultEditorKeyMap,
 		help:         help.New(),
 		config:       config,
+		store:        st,
 		inputs: []textinput.Model{
 			newTextInput(defaultSnippetFolder + " "),
 			newTextInput(defaultSnippetName + " "),
 			newTextInput(config.DefaultLanguage),
 		},
-		tagsInput: newTextInput("Tags"),
-	}
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	model, err := p.Run()
-	if err != nil {
-		return err
-	}
-	fm, ok := model.(*Model)
-	if !ok {
-		return err
-	}
-	var allSnippets []list.Item
-	for _, list := range fm.Lists {
-		allSnippets = append(allSnippets, list.Items()...)
-	}
-	b, err := json.Marshal(allSnippets)
-	if err != nil {
-		return err
+		collapsed:   collapsed,
+		orders:      map[string]listOrder{},
+		positions:   map[string]int{},
+		tagsInput:   newTextInput("Tags"),
+		folderInput: newTextInput("New folder"),
+		searchInput: newTextInput("Search contents"),
+	}
+	m.finder = newFinder(defaultStyles.Finder.Selected)
+	for name, order := range state.Orders {
+		m.orders[name] = parseOrder(order)
+	}
+	for folder, li := range m.Lists {
+		m.recordManualOrder(li)
+		m.sortList(folder)
+	}
+	m.trash, _ = st.ListTrash()
+	m.searchInput.Prompt = "Grep: "
+	m.searchInput.PromptStyle = defaultStyles.Snippets.Focused.Title
+
+	// Restore the last selected folder, or select the first actual folder.
+	m.expandFolder(Folder(state.CurrentFolder))
+	m.Folders.SetItems(m.folderItems())
+	selected := -1
+	for idx, item := range m.Folders.Items() {
+		if item.FilterValue() == state.CurrentFolder {
+			selected = idx
+			break
+		}
+		if _, ok := item.(Folder); ok && selected < 0 {
+			selected = idx
+		}
 	}
-	err = os.WriteFile(filepath.Join(config.Home, config.File), b, os.ModePerm)
-	if err != nil {
-		return err
+	m.Folders.Select(selected)
+	for idx, item := range m.List().Items() {
+		if s, ok := item.(store.Snippet); ok && (s.ID == state.CurrentSnippet || s.File == state.CurrentSnippet) {
+			m.List().Select(idx)
+			break
+		}
 	}
-	return nil
+	return m
 }
 
 func newList(items []list.Item, height int, styles SnippetsBaseStyle) *list.Model {
-	snippetList := list.New(items, snippetDelegate{styles, navigatingState}, 25, height)
+	snippetList := list.New(items, snippetDelegate{styles, navigatingState, listOrder{}}, 25, height)
 	snippetList.SetShowHelp(false)
 	snippetList.SetShowFilter(false)
 	snippetList.SetShowTitle(false)
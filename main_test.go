@@ -1,10 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/maaslalani/nap/store"
+	"github.com/sahilm/fuzzy"
 )
 
 func TestCLI(t *testing.T) {
@@ -23,7 +31,11 @@ func TestCLI(t *testing.T) {
 		runCLI([]string{"foo/bar.baz"})
 
 		cfg := readConfig()
-		snippets := readSnippets(cfg)
+		snippets, err := store.New(cfg.Home, cfg.File).List()
+		if err != nil {
+			t.Logf("could not read snippets: %v", err)
+			t.FailNow()
+		}
 
 		if len(snippets) != 1 {
 			t.Logf("snippet count is incorrect: got %d but want 1", len(snippets))
@@ -71,6 +83,34 @@ func TestCLI(t *testing.T) {
 		}
 	})
 
+	t.Run("id", func(t *testing.T) {
+		cfg := readConfig()
+		snippets, err := store.New(cfg.Home, cfg.File).List()
+		if err != nil || len(snippets) != 1 || snippets[0].ID == "" {
+			t.Logf("snippet has no ID: %v (%v)", snippets, err)
+			t.FailNow()
+		}
+
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Logf("could not open pipe: %v", err)
+			t.FailNow()
+		}
+		os.Stdout = w
+		runCLI([]string{snippets[0].ID})
+		w.Close()
+		out, err := io.ReadAll(r)
+		if err != nil {
+			t.Log("could not read stdout")
+			t.FailNow()
+		}
+
+		if string(out) != "foo bar baz" {
+			t.Logf(`snippet is incorrect: got %q but want "foo bar baz"`, string(out))
+			t.FailNow()
+		}
+	})
+
 	t.Run("list", func(t *testing.T) {
 		r, w, err := os.Pipe()
 		if err != nil {
@@ -93,43 +133,240 @@ func TestCLI(t *testing.T) {
 	})
 }
 
-func TestScan(t *testing.T) {
-	tmp := tmpHome(t)
+func TestFilters(t *testing.T) {
+	tmpHome(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Logf("could not open pipe: %v", err)
+		t.FailNow()
+	}
+	os.Stdin = r
+	w.WriteString("tagged")
+	w.Close()
+	runCLI([]string{"--tag", "foo,#bar", "qux/tagged.go"})
 
-	cfg := readConfig()
-	snippets := readSnippets(cfg)
-	snippets = scanSnippets(cfg, snippets)
-	initNum := len(snippets)
+	r, w, err = os.Pipe()
+	if err != nil {
+		t.Logf("could not open pipe: %v", err)
+		t.FailNow()
+	}
+	os.Stdin = r
+	w.WriteString("untagged")
+	w.Close()
+	runCLI([]string{"--favorites", "qux/untagged.go"})
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "list", Args: []string{"list", "--tag", "bar"}, Want: "qux/tagged.go\n"},
+		{Name: "list all tags", Args: []string{"list", "--tag", "foo", "--tag", "bar"}, Want: "qux/tagged.go\n"},
+		{Name: "list unknown tag", Args: []string{"list", "--tag", "baz"}, Want: ""},
+		{Name: "list favorites", Args: []string{"list", "--favorites"}, Want: "qux/untagged.go\n"},
+		{Name: "list favorites with tag", Args: []string{"list", "--favorites", "--tag", "foo"}, Want: ""},
+		{Name: "find", Args: []string{"--tag", "foo", "qux"}, Want: "tagged"},
+		{Name: "find favorite", Args: []string{"--favorites", "qux"}, Want: "untagged"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
 
-	tmpSnippetFolder := filepath.Join(tmp, "foo")
-	tmpSnippet := filepath.Join(tmpSnippetFolder, "bar.baz")
-	if err := os.MkdirAll(tmpSnippetFolder, os.ModePerm); err != nil {
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
 		t.Logf("could not create snippet folder: %v", err)
 		t.FailNow()
 	}
-	if err := os.WriteFile(tmpSnippet, []byte("foo bar baz"), os.ModePerm); err != nil {
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "bar.go"), []byte("package bar\n\nfunc Baz() {}\n"), os.ModePerm); err != nil {
 		t.Logf("could not create snippet: %v", err)
 		t.FailNow()
 	}
 
-	snippets = scanSnippets(cfg, snippets)
-	if len(snippets) != initNum+1 {
-		t.Logf("incorrect number of snippets after initial scanning: want %d but got %d", initNum+1, len(snippets))
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "regex", Args: []string{"search", "B.z"}, Want: "foo/bar.go:3:func Baz() {}\n"},
+		{Name: "fixed", Args: []string{"search", "-F", "B.z"}, Want: ""},
+		{Name: "ignore case", Args: []string{"search", "-i", "BAR"}, Want: "foo/bar.go:1:package bar\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
+
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := fmt.Sprint(parseTags("go, #http +cli  go"))
+	if want := "[go http cli]"; got != want {
+		t.Logf("tags are incorrect: want %s but got %s", want, got)
+		t.FailNow()
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Notes/Hello.go", "[Notes Hello go]"},
+		{"Hello.go", "[misc Hello go]"},
+		{"work/go/http.go", "[work/go http go]"},
+		{"work/go/http", "[work/go http go]"},
+	}
+	for _, tt := range tests {
+		folder, name, language := parseName(tt.in)
+		if got := fmt.Sprint([]string{folder, name, language}); got != tt.want {
+			t.Logf("name %q is parsed incorrectly: want %s but got %s", tt.in, tt.want, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestFolderTree(t *testing.T) {
+	m := &Model{Lists: map[Folder]*list.Model{}, collapsed: map[Folder]bool{}}
+	for _, folder := range []Folder{"work/go/http", "work-old", "work/py", "misc"} {
+		m.Lists[folder] = newList(nil, 10, SnippetsBaseStyle{})
+	}
+
+	got := fmt.Sprint(m.visibleFolders())
+	if want := "[misc work work/go work/go/http work/py work-old]"; got != want {
+		t.Logf("folders are incorrect: want %s but got %s", want, got)
 		t.FailNow()
 	}
 
-	if err := os.Remove(tmpSnippet); err != nil {
-		t.Logf("could not remove snippet: %v", err)
+	m.collapsed["work/go"] = true
+	m.collapsed["misc"] = true
+	got = fmt.Sprint(m.visibleFolders())
+	if want := "[misc work work/go work/py work-old]"; got != want {
+		t.Logf("collapsed folders are incorrect: want %s but got %s", want, got)
 		t.FailNow()
 	}
+	if m.collapsed["misc"] {
+		t.Log("folder without nested folders should not stay collapsed")
+		t.FailNow()
+	}
+}
 
-	snippets = scanSnippets(cfg, snippets)
-	if len(snippets) != initNum {
-		t.Logf("incorrect number of snippets after follow-up scanning: want %d but got %d", initNum, len(snippets))
+func TestDeleteLastFolder(t *testing.T) {
+	st := store.New(t.TempDir(), "snippets.json")
+	if err := st.Create(store.Snippet{Folder: "go", Name: "main", File: "main.go", Language: "go"}, []byte("package main")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	snippets, _ := st.List()
+	m := newModel(Config{}, st, snippets, State{CurrentFolder: "go"})
+	if err := m.deleteFolder(); err != nil {
+		t.Logf("could not delete folder: %v", err)
+		t.FailNow()
+	}
+
+	_ = m.View()
+	if m.List() == nil || len(m.List().Items()) != 0 {
+		t.Log("the placeholder folder should have an empty list")
+		t.FailNow()
+	}
+	if len(m.trash) != 1 {
+		t.Logf("snippet should be in the trash: got %v", m.trash)
+		t.FailNow()
+	}
+}
+
+func TestSelectCollapsedSnippet(t *testing.T) {
+	st := store.New(t.TempDir(), "snippets.json")
+	for _, snippet := range []store.Snippet{
+		{Folder: "misc", Name: "notes", File: "notes.md", Language: "md"},
+		{Folder: "work/go", Name: "main", File: "main.go", Language: "go"},
+	} {
+		if err := st.Create(snippet, nil); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	snippets, _ := st.List()
+	m := newModel(Config{}, st, snippets, State{CurrentFolder: "misc", CollapsedFolders: []string{"work"}})
+
+	snippet, _ := st.Get("work/go/main.go")
+	m.selectSnippet(snippet)
+	if selected := m.Folders.SelectedItem(); selected == nil || selected.FilterValue() != "work/go" {
+		t.Logf("folder of the snippet should be selected: got %v", selected)
+		t.FailNow()
+	}
+	if selected, ok := m.List().SelectedItem().(store.Snippet); !ok || !selected.Same(snippet) {
+		t.Logf("snippet should be selected: got %v", m.List().SelectedItem())
 		t.FailNow()
 	}
 }
 
+func TestSaveSnippetTags(t *testing.T) {
+	tmp := t.TempDir()
+	st := store.New(tmp, "snippets.json")
+	if err := saveSnippet("echo hi", []string{"misc/hi.sh"}, snippetFilter{}, st); err != nil {
+		t.Logf("could not save snippet: %v", err)
+		t.FailNow()
+	}
+	b, err := os.ReadFile(filepath.Join(tmp, "snippets.json"))
+	if err != nil || !strings.Contains(string(b), `"tags":[]`) {
+		t.Logf("snippet should have empty tags: got %s (%v)", b, err)
+		t.FailNow()
+	}
+}
+
+func TestFinderScroll(t *testing.T) {
+	f := finder{matches: make([]fuzzy.Match, 10)}
+	for i := range f.matches {
+		f.matches[i].Index = i
+	}
+	for _, tc := range []struct{ cursor, first int }{{0, 0}, {4, 2}, {9, 7}, {8, 7}, {3, 3}} {
+		f.cursor = tc.cursor
+		shown := f.scroll(3)
+		if len(shown) != 3 || shown[0].Index != tc.first {
+			t.Logf("matches shown with the cursor on %d are incorrect: want 3 from %d but got %v", tc.cursor, tc.first, shown)
+			t.FailNow()
+		}
+	}
+}
+
 func tmpHome(t *testing.T) string {
 	t.Helper()
 
@@ -140,3 +377,264 @@ func tmpHome(t *testing.T) string {
 	}
 	return tmp
 }
+
+func TestHistory(t *testing.T) {
+	tmp := tmpHome(t)
+	st := store.New(tmp, "snippets.json")
+	snippet := store.Snippet{Folder: "foo", Name: "bar", File: "bar.txt", Language: "txt"}
+	if err := st.Create(snippet, []byte("one\n")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	if err := st.Write(snippet, []byte("two\n")); err != nil {
+		t.Logf("could not write snippet: %v", err)
+		t.FailNow()
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "diff", Args: []string{"history", "foo/bar", "--rev", "2"}, Want: "@@ -1,1 +1,1 @@\n-one\n+two\n"},
+		{Name: "restore", Args: []string{"restore", "--rev", "1", "foo/bar"}, Want: ""},
+		{Name: "restored", Args: []string{"foo/bar"}, Want: "one\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
+
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+
+	revisions, _ := st.History(snippet)
+	if len(revisions) != 3 {
+		t.Logf("restoring should record a revision: want 3 but got %d", len(revisions))
+		t.FailNow()
+	}
+}
+
+func TestTrash(t *testing.T) {
+	tmp := tmpHome(t)
+	st := store.New(tmp, "snippets.json")
+	snippet := store.Snippet{Folder: "foo", Name: "bar", File: "bar.txt", Language: "txt"}
+	if err := st.Create(snippet, []byte("bar")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	if _, err := st.Trash(snippet); err != nil {
+		t.Logf("could not trash snippet: %v", err)
+		t.FailNow()
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "restore", Args: []string{"trash", "restore", "bar"}, Want: "Restored foo/bar.txt\n"},
+		{Name: "restored", Args: []string{"foo/bar"}, Want: "bar"},
+		{Name: "empty", Args: []string{"trash", "list"}, Want: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
+
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tmp := tmpHome(t)
+	file := filepath.Join(t.TempDir(), "fizz.go")
+	if err := os.WriteFile(file, []byte("package fizz"), 0o644); err != nil {
+		t.Logf("could not write file: %v", err)
+		t.FailNow()
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+		Code int
+	}{
+		{Name: "add", Args: []string{"add", "--tag", "go", file, "work/"}, Want: "Added work/fizz.go\n"},
+		{Name: "add exists", Args: []string{"add", file, "work/"}, Code: 1},
+		{Name: "show", Args: []string{"show", "fizz"}, Want: "package fizz"},
+		{Name: "mv", Args: []string{"mv", "fizz", "misc/buzz.go"}, Want: "Moved work/fizz.go to misc/buzz.go\n"},
+		{Name: "cp", Args: []string{"cp", "misc/buzz.go", "work/"}, Want: "Copied misc/buzz.go to work/buzz.go\n"},
+		{Name: "mv exists", Args: []string{"mv", "work/buzz.go", "misc/"}, Code: 1},
+		{Name: "exact", Args: []string{"rm", "--exact", "buzz"}, Code: exitNotFound},
+		{Name: "rm", Args: []string{"rm", "--exact", "work/buzz.go"}, Want: "Moved work/buzz.go to the trash\n"},
+		{Name: "usage", Args: []string{"mv", "misc/buzz.go"}, Code: exitUsage},
+		{Name: "invalid flag", Args: []string{"show", "--bogus", "misc/buzz.go"}, Code: exitUsage},
+		{Name: "folder usage", Args: []string{"folder", "rename", "misc"}, Code: exitUsage},
+		{Name: "search usage", Args: []string{"search"}, Code: exitUsage},
+	}
+	if exitUsage == 1 || exitUsage == exitNotFound || exitUsage == exitAmbiguous {
+		t.Logf("usage errors should have their own exit code: got %d", exitUsage)
+		t.FailNow()
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			code := runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
+
+			if code != tc.Code {
+				t.Logf("exit code is incorrect: want %d but got %d", tc.Code, code)
+				t.FailNow()
+			}
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+
+	st := store.New(tmp, "snippets.json")
+	snippet, err := st.Get("misc/buzz.go")
+	if err != nil {
+		t.Logf("could not find moved snippet: %v", err)
+		t.FailNow()
+	}
+	if fmt.Sprint(snippet.Tags) != "[go]" {
+		t.Logf("tags are incorrect: want [go] but got %v", snippet.Tags)
+		t.FailNow()
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Logf("could not open pipe: %v", err)
+		t.FailNow()
+	}
+	os.Stdout = w
+	runCLI([]string{"show", "--meta", snippet.ID})
+	w.Close()
+	out, _ := io.ReadAll(r)
+	want := fmt.Sprintf("id:       %s\nname:     buzz\nfolder:   misc\n", snippet.ID)
+	if !strings.HasPrefix(string(out), want) {
+		t.Logf("metadata is incorrect: want prefix %q but got %q", want, string(out))
+		t.FailNow()
+	}
+}
+
+func TestOutput(t *testing.T) {
+	tmp := tmpHome(t)
+	st := store.New(tmp, "snippets.json")
+	date := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, snippet := range []store.Snippet{
+		{ID: "a1", Folder: "work", Name: "beta", File: "beta.go", Language: "go", Tags: []string{"x", "y"}, Created: date, Modified: date.Add(2 * time.Hour)},
+		{ID: "b2", Folder: "misc", Name: "alpha", File: "alpha.sh", Language: "sh", Tags: []string{}, Created: date.Add(time.Hour), Modified: date.Add(time.Hour), LastUsed: date},
+		{ID: "c3", Folder: "misc", Name: "gamma", File: "gamma.md", Language: "md", Tags: []string{}, Favorite: true, Created: date.Add(-time.Hour), Modified: date.Add(-time.Hour)},
+	} {
+		if err := st.Create(snippet, []byte(fmt.Sprint(i))); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "template", Args: []string{"list", "--format", "{{.Folder}}/{{.Name}}"}, Want: "misc/gamma\nmisc/alpha\nwork/beta\n"},
+		{Name: "date", Args: []string{"list", "--sort", "date", "--format", "{{.ID}}"}, Want: "a1\nb2\nc3\n"},
+		{Name: "created", Args: []string{"list", "--sort", "created", "--format", "{{.ID}}"}, Want: "b2\na1\nc3\n"},
+		{Name: "used", Args: []string{"list", "--sort", "used", "--format", "{{.ID}}"}, Want: "b2\nc3\na1\n"},
+		{Name: "name", Args: []string{"list", "--sort", "name", "--reverse", "--limit", "2"}, Want: "misc/gamma.md\nwork/beta.go\n"},
+		{Name: "folder", Args: []string{"list", "--sort", "folder", "--format", "{{.ID}}"}, Want: "b2\nc3\na1\n"},
+		{Name: "tsv", Args: []string{"list", "work", "--format", "tsv"}, Want: "a1\twork\tbeta\tgo\tx,y\tfalse\t2023-01-02T03:04:05Z\t2023-01-02T05:04:05Z\t\t" + filepath.Join(tmp, "work", "beta.go") + "\n"},
+		{Name: "show", Args: []string{"show", "--format", "{{.Favorite}} {{.FilePath}}", "gamma"}, Want: "true " + filepath.Join(tmp, "misc", "gamma.md") + "\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
+
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+
+	var b strings.Builder
+	snippets, _ := st.List()
+	if err := printSnippets(&b, st, snippets, outputOptions{format: "json", limit: 1}); err != nil {
+		t.Logf("could not print snippets: %v", err)
+		t.FailNow()
+	}
+	var infos []map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &infos); err != nil {
+		t.Logf("output is not valid JSON: %v", err)
+		t.FailNow()
+	}
+	if len(infos) != 1 || infos[0]["id"] != "c3" || infos[0]["path"] != filepath.Join(tmp, "misc", "gamma.md") {
+		t.Logf("JSON output is incorrect: got %v", infos)
+		t.FailNow()
+	}
+
+	if err := printSnippets(&b, st, snippets, outputOptions{sort: "size"}); err == nil {
+		t.Log("invalid sort should fail")
+		t.FailNow()
+	}
+}
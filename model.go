@@ -3,9 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"math/rand"
-	"os"
-	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -18,6 +16,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/maaslalani/nap/store"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
@@ -43,6 +42,15 @@ const (
 	quittingState
 	editingState
 	editingTagsState
+	searchingState
+	findingState
+	fillingTemplateState
+	historyState
+	overwritingState
+	editingFolderState
+	deletingFolderState
+	editingContentState
+	savingContentState
 )
 
 type input int
@@ -58,25 +66,68 @@ const (
 type Model struct {
 	// the config map.
 	config Config
+	// the store the snippets are read from and written to.
+	store *store.Store
+	// the snippets as last loaded from the store, to merge the changes made
+	// by other programs, which the watcher reports, with the lists.
+	base    []store.Snippet
+	watcher *store.Watcher
+	stale   bool
 	// the key map.
 	keys KeyMap
 	// the help model.
 	help help.Model
-	// the height of the terminal.
+	// the height and width of the terminal.
 	height int
+	width  int
 	// the working directory.
 	Workdir string
 	// the List of snippets to display to the user.
 	Lists map[Folder]*list.Model
 	// the list of Folders to display to the user.
 	Folders list.Model
+	// the list of snippets gathered by the selected view, if any.
+	viewList *list.Model
+	viewName string
 	// the viewport of the Code snippet.
 	Code        viewport.Model
 	LineNumbers viewport.Model
+	// the editor of the content of the selected snippet.
+	editor     contentEditor
+	editorKeys EditorKeyMap
 	// the input for snippet folder, name, language
 	activeInput input
 	inputs      []textinput.Model
 	tagsInput   textinput.Model
+	searchInput textinput.Model
+	// the regular expression of the content search and the view of the
+	// snippets matching it, if any.
+	search     *regexp.Regexp
+	searchView view
+	// the popup to find snippets across every folder.
+	finder finder
+	// the form to fill in the placeholders of a template before copying it.
+	placeholders   []placeholder
+	templateInputs []textinput.Model
+	templateInput  int
+	// the revisions of the selected snippet and the one being browsed.
+	revisions []store.Revision
+	revision  int
+	// the name of the folder to create, rename or merge into.
+	folderInput  textinput.Model
+	folderAction folderAction
+	// the folders whose nested folders are hidden.
+	collapsed map[Folder]bool
+	// the rename waiting for the user to resolve a collision.
+	moveFrom, moveTo store.Snippet
+	// the deleted snippets and the last one deleted, to undo it.
+	trash       []store.Trashed
+	lastDeleted *store.Trashed
+	// the orders of the folders and views whose snippets are sorted or
+	// grouped, and the positions of their snippets by ID in the manual order,
+	// which is the order saved.
+	orders    map[string]listOrder
+	positions map[string]int
 	// the current active pane of focus.
 	pane pane
 	// the current state / action of the application.
@@ -89,20 +140,16 @@ type Model struct {
 
 // Init initialzes the application model.
 func (m *Model) Init() tea.Cmd {
-	rand.Seed(time.Now().Unix())
-
 	m.Folders.Styles.Title = m.FoldersStyle.Title
 	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
 	m.updateKeyMap()
 
-	return func() tea.Msg {
-		return updateContentMsg(m.selectedSnippet())
-	}
+	return tea.Batch(m.updateContent(), m.waitForChanges())
 }
 
 // updateContentMsg tells the application to update the content view with the
 // given snippet.
-type updateContentMsg Snippet
+type updateContentMsg store.Snippet
 
 // updateContent instructs the application to fetch the latest contents of the
 // snippet file.
@@ -128,6 +175,9 @@ func (m *Model) updateFolders() tea.Cmd {
 	}
 }
 
+// errorMsg tells the application to display an error in the content view.
+type errorMsg string
+
 // changeStateMsg tells the application to enter a different state.
 type changeStateMsg struct{ newState state }
 
@@ -144,13 +194,29 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case updateFoldersMsg:
 		setItemsCmd := m.Folders.SetItems(msg.items)
 		m.Folders.Select(msg.selectedFolderIndex)
+		m.refreshView()
 		var cmd tea.Cmd
 		m.Folders, cmd = m.Folders.Update(msg)
 		return m, tea.Batch(setItemsCmd, cmd)
 	case updateContentMsg:
 		return m.updateContentView(msg)
+	case errorMsg:
+		m.displayError(string(msg))
+		return m, nil
+	case snippetEditedMsg:
+		snippet := store.Snippet(msg)
+		var cmd tea.Cmd
+		if m.selectedSnippet().Same(snippet) {
+			cmd = m.setSelectedSnippet(snippet)
+		}
+		return m, tea.Batch(cmd, m.updateFolders(), m.updateContent())
+	case storeChangedMsg:
+		m.stale = true
+		return m, tea.Batch(m.waitForChanges(), m.reloadWhenIdle())
+	case reloadMsg:
+		return m, m.reloadWhenIdle()
 	case changeStateMsg:
-		m.List().SetDelegate(snippetDelegate{m.ListStyle, msg.newState})
+		m.List().SetDelegate(snippetDelegate{m.ListStyle, msg.newState, m.listOrder()})
 
 		var cmd tea.Cmd
 
@@ -159,6 +225,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		wasEditing := m.state == editingState
+		wasEditingTags := m.state == editingTagsState
+		wasSearching := m.state == searchingState
 		wasPasting := m.state == pastingState
 		wasCreating := m.state == creatingState
 		m.state = msg.newState
@@ -171,10 +239,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.updateContent()
 			}
 
+			if wasSearching {
+				m.searchInput.Blur()
+				m.pane = snippetPane
+				cmd = m.updateContent()
+			}
+
 			if wasEditing {
 				m.blurInputs()
-				i := m.List().Index()
-				snippet := m.selectedSnippet()
+				oldSnippet := m.selectedSnippet()
+				snippet := oldSnippet
 				if m.inputs[nameInput].Value() != "" {
 					snippet.Name = m.inputs[nameInput].Value()
 				} else {
@@ -190,27 +264,36 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					snippet.Language = m.config.DefaultLanguage
 				}
-				file := fmt.Sprintf("%s.%s", snippet.Name, snippet.Language)
-				snippet.File = file
-				newPath := filepath.Join(m.config.Home, snippet.Path())
-				_ = os.MkdirAll(filepath.Dir(newPath), os.ModePerm)
-				_ = os.Rename(m.selectedSnippetFilePath(), newPath)
-				setCmd := m.List().SetItem(i, snippet)
+				snippet.File = store.FileName(snippet.Name, snippet.Language)
+				cmd = m.moveSnippet(oldSnippet, snippet)
+			}
+
+			if wasEditingTags {
+				m.tagsInput.Blur()
+				snippet := m.selectedSnippet()
+				snippet.Tags = parseTags(m.tagsInput.Value())
 				m.pane = snippetPane
-				cmd = tea.Batch(setCmd, m.updateFolders(), m.updateContent())
+				if err := m.store.Update(snippet); err != nil {
+					m.displayError("Unable to save tags: " + err.Error())
+				} else {
+					setCmd := m.setSelectedSnippet(snippet)
+					cmd = tea.Batch(setCmd, m.updateFolders(), m.updateContent())
+				}
 			}
 		case pastingState:
 			content, err := clipboard.ReadAll()
 			if err != nil {
 				return m, changeState(navigatingState)
 			}
-			f, err := os.OpenFile(m.selectedSnippetFilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				return m, changeState(navigatingState)
+			snippet := m.selectedSnippet()
+			if err := m.store.Append(snippet, []byte(content)); err == nil {
+				if appended, err := m.store.Get(snippet.Path()); err != nil {
+					m.displayError("Unable to read snippet: " + err.Error())
+				} else {
+					cmd = m.setSelectedSnippet(appended)
+				}
 			}
-			defer f.Close()
-			f.WriteString(content)
-			return m, changeState(navigatingState)
+			return m, tea.Batch(cmd, changeState(navigatingState))
 		case deletingState:
 			m.state = deletingState
 		case editingState:
@@ -224,6 +307,23 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.inputs[languageInput].SetValue(snippet.Language)
 			cmd = m.focusInput(m.activeInput)
+		case editingTagsState:
+			m.pane = contentPane
+			m.tagsInput.SetValue(strings.Join(m.selectedSnippet().Tags, " "))
+			m.tagsInput.CursorEnd()
+			cmd = m.tagsInput.Focus()
+		case searchingState:
+			m.pane = snippetPane
+			m.searchInput.CursorEnd()
+			cmd = m.searchInput.Focus()
+		case historyState:
+			m.displayRevision()
+		case editingContentState:
+			m.pane = contentPane
+		case overwritingState:
+			m.displayCollision()
+		case editingFolderState:
+			cmd = m.folderInput.Focus()
 		case creatingState:
 		case copyingState:
 			m.pane = snippetPane
@@ -239,9 +339,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	case tea.WindowSizeMsg:
 		m.height = msg.Height - 4
+		m.width = msg.Width
 		for _, li := range m.Lists {
 			li.SetHeight(m.height)
 		}
+		if m.viewList != nil {
+			m.viewList.SetHeight(m.height)
+		}
 		m.Folders.SetHeight(m.height)
 		m.Code.Height = m.height
 		m.LineNumbers.Height = m.height
@@ -256,17 +360,22 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.state == deletingState {
 			switch {
 			case key.Matches(msg, m.keys.Confirm):
-				_ = os.Remove(m.selectedSnippetFilePath())
-				m.List().RemoveItem(m.List().Index())
+				m.trashSnippet()
 				m.state = navigatingState
 				m.updateKeyMap()
-				return m, tea.Batch(changeState(navigatingState), func() tea.Msg {
+				return m, tea.Batch(changeState(navigatingState), m.updateFolders(), func() tea.Msg {
 					return updateContentMsg(m.selectedSnippet())
 				})
 			case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
 				return m, changeState(navigatingState)
 			}
 			return m, nil
+		} else if m.state == overwritingState {
+			return m, m.updateOverwrite(msg)
+		} else if m.state == editingFolderState {
+			return m, m.updateFolderInput(msg)
+		} else if m.state == deletingFolderState {
+			return m, m.updateDeleteFolder(msg)
 		} else if m.state == copyingState {
 			return m, changeState(navigatingState)
 		} else if m.state == editingState {
@@ -280,9 +389,51 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, cmd)
 			}
 			return m, tea.Batch(cmds...)
+		} else if m.state == editingTagsState {
+			switch msg.String() {
+			case "esc", "enter":
+				return m, changeState(navigatingState)
+			case "tab":
+				m.completeTag()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.tagsInput, cmd = m.tagsInput.Update(msg)
+			return m, cmd
+		} else if m.state == findingState {
+			return m, m.updateFinder(msg)
+		} else if m.state == fillingTemplateState {
+			return m, m.updateTemplateForm(msg)
+		} else if m.state == historyState {
+			return m, m.updateHistory(msg)
+		} else if m.state == editingContentState {
+			return m, m.updateEditor(msg)
+		} else if m.state == savingContentState {
+			return m, m.updateSaving(msg)
+		} else if m.state == searchingState {
+			switch msg.String() {
+			case "esc":
+				return m, changeState(navigatingState)
+			case "enter":
+				m.searchContents()
+				return m, changeState(navigatingState)
+			}
+			var cmd tea.Cmd
+			m.searchInput, cmd = m.searchInput.Update(msg)
+			return m, cmd
 		}
 
+		lastDeleted := m.lastDeleted
+		m.lastDeleted = nil
+
 		switch {
+		case key.Matches(msg, m.keys.RestoreSnippet):
+			if t, ok := m.List().SelectedItem().(trashItem); ok {
+				return m, m.restoreTrashed(t.Trashed)
+			}
+			if lastDeleted != nil {
+				return m, m.restoreTrashed(*lastDeleted)
+			}
 		case key.Matches(msg, m.keys.NextPane):
 			m.nextPane()
 		case key.Matches(msg, m.keys.PreviousPane):
@@ -298,11 +449,28 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.moveSnippetDown()
 		case key.Matches(msg, m.keys.MoveSnippetUp):
 			m.moveSnippetUp()
+		case key.Matches(msg, m.keys.SortSnippets):
+			m.cycleSort()
+			return m, m.updateContent()
+		case key.Matches(msg, m.keys.GroupSnippets):
+			m.cycleGroup()
+			return m, m.updateContent()
 		case key.Matches(msg, m.keys.PasteSnippet):
 			return m, changeState(pastingState)
 		case key.Matches(msg, m.keys.RenameSnippet):
 			m.activeInput = nameInput
 			return m, changeState(editingState)
+		case key.Matches(msg, m.keys.NewFolder):
+			return m, m.editFolder(createFolderAction)
+		case key.Matches(msg, m.keys.RenameFolder):
+			return m, m.editFolder(renameFolderAction)
+		case key.Matches(msg, m.keys.MergeFolder):
+			return m, m.editFolder(mergeFolderAction)
+		case key.Matches(msg, m.keys.DeleteFolder):
+			return m, changeState(deletingFolderState)
+		case key.Matches(msg, m.keys.ToggleFolder):
+			m.toggleFolder()
+			return m, m.updateContent()
 		case key.Matches(msg, m.keys.ChangeFolder):
 			m.pane = snippetPane
 			cmd := m.updateActivePane(msg)
@@ -326,15 +494,21 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.SetLanguage):
 			m.activeInput = languageInput
 			return m, changeState(editingState)
+		case key.Matches(msg, m.keys.TagSnippet):
+			return m, changeState(editingTagsState)
+		case key.Matches(msg, m.keys.StarSnippet):
+			snippet := m.selectedSnippet()
+			snippet.Favorite = !snippet.Favorite
+			if err := m.store.Update(snippet); err != nil {
+				m.displayError("Unable to save favorite: " + err.Error())
+				return m, nil
+			}
+			return m, tea.Batch(m.setSelectedSnippet(snippet), m.updateFolders())
 		case key.Matches(msg, m.keys.CopySnippet):
-			return m, func() tea.Msg {
-				content, err := os.ReadFile(m.selectedSnippetFilePath())
-				if err != nil {
-					return changeStateMsg{navigatingState}
-				}
-				clipboard.WriteAll(string(content))
-				return changeStateMsg{copyingState}
+			if p := m.selectedPlaceholders(); len(p) > 0 {
+				return m, m.fillTemplate(p)
 			}
+			return m, m.copySnippet(nil)
 		case key.Matches(msg, m.keys.DeleteSnippet):
 			m.pane = snippetPane
 			m.updateActivePane(msg)
@@ -342,11 +516,23 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, changeState(deletingState)
 		case key.Matches(msg, m.keys.EditSnippet):
 			return m, m.editSnippet()
+		case key.Matches(msg, m.keys.EditInline):
+			return m, m.openEditor()
 		case key.Matches(msg, m.keys.Search):
 			m.pane = snippetPane
+		case key.Matches(msg, m.keys.SearchContent):
+			return m, changeState(searchingState)
+		case key.Matches(msg, m.keys.FindSnippet):
+			return m, m.openFinder()
+		case key.Matches(msg, m.keys.ShowHistory):
+			return m, m.openHistory()
 		}
 	}
 
+	if m.state == editingContentState {
+		return m, m.editor.update(msg)
+	}
+
 	m.updateKeyMap()
 	cmd := m.updateActivePane(msg)
 	return m, cmd
@@ -369,7 +555,7 @@ func (m *Model) focusInput(i input) tea.Cmd {
 // selectedSnippetFilePath returns the file path of the snippet that is
 // currently selected.
 func (m *Model) selectedSnippetFilePath() string {
-	return filepath.Join(m.config.Home, m.selectedSnippet().Path())
+	return m.store.FilePath(m.selectedSnippet())
 }
 
 // nextPane sets the next pane to be active.
@@ -385,13 +571,46 @@ func (m *Model) previousPane() {
 	}
 }
 
-// editSnippet opens the editor with the selected snippet file path.
+// editSnippet opens the editor with the selected snippet file path, at the
+// first match of the content search or the first line in view, or edits the
+// content in the content pane if the built-in editor is preferred or no
+// editor is installed.
 func (m *Model) editSnippet() tea.Cmd {
-	return tea.ExecProcess(editorCmd(m.selectedSnippetFilePath()), func(err error) tea.Msg {
+	if m.config.BuiltinEditor || !hasEditor(m.config) {
+		return m.openEditor()
+	}
+	snippet := m.selectedSnippet()
+	before, _ := m.store.Content(snippet)
+	cmd := editorCmd(m.config, m.selectedSnippetFilePath(), m.editorLine(before))
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if after, _ := m.store.Content(snippet); !bytes.Equal(before, after) {
+			if edited, err := m.store.Edited(snippet); err == nil {
+				return snippetEditedMsg(edited)
+			}
+		}
 		return updateContentMsg(m.selectedSnippet())
 	})
 }
 
+// snippetEditedMsg tells the application that the content of the snippet was
+// changed in the editor.
+type snippetEditedMsg store.Snippet
+
+// editorLine returns the line to open the selected snippet at in the editor:
+// the first match of the content search, the first line in view if it is
+// scrolled, or zero.
+func (m *Model) editorLine(content []byte) int {
+	if v, ok := m.Folders.SelectedItem().(view); ok && m.search != nil && v.name == m.searchView.name {
+		if matches := store.Grep(content, m.search); len(matches) > 0 {
+			return matches[0].Line
+		}
+	}
+	if m.Code.YOffset > 0 {
+		return m.Code.YOffset + 1
+	}
+	return 0
+}
+
 func (m *Model) noContentHints() []keyHint {
 	return []keyHint{
 		{m.keys.EditSnippet, "edit contents"},
@@ -408,7 +627,7 @@ func (m *Model) updateFoldersView() tea.Msg {
 	selectedFolderIndex := m.Folders.Index()
 	for folder, li := range m.Lists {
 		for i, item := range li.Items() {
-			snippet, ok := item.(Snippet)
+			snippet, ok := item.(store.Snippet)
 			if !ok {
 				continue
 			}
@@ -425,16 +644,25 @@ func (m *Model) updateFoldersView() tea.Msg {
 			}
 		}
 	}
-	var folderItems []list.Item
-
-	foldersSlice := maps.Keys(m.Lists)
-	slices.Sort(foldersSlice)
-	for i, folder := range foldersSlice {
-		folderItems = append(folderItems, Folder(folder))
-		if folder == selectedFolder {
-			selectedFolderIndex = i
+	if selectedFolder != "" {
+		m.expandFolder(selectedFolder)
+	}
+	folderItems := m.folderItems()
+	selected := m.Folders.SelectedItem()
+	if _, ok := selected.(view); selectedFolder != "" && !ok {
+		selected = selectedFolder
+	}
+	if selected != nil {
+		for i, item := range folderItems {
+			if item.FilterValue() == selected.FilterValue() {
+				selectedFolderIndex = i
+				break
+			}
 		}
 	}
+	if selectedFolderIndex >= len(folderItems) {
+		selectedFolderIndex = len(folderItems) - 1
+	}
 
 	return updateFoldersMsg{
 		items:               folderItems,
@@ -442,6 +670,128 @@ func (m *Model) updateFoldersView() tea.Msg {
 	}
 }
 
+// folderItems returns the items of the folder pane: the content search
+// results, the favorites and the recently used snippets, if any, pinned at
+// the top, then the tree of folders followed by a view for each tag and the
+// trash.
+func (m *Model) folderItems() []list.Item {
+	var items []list.Item
+	if m.search != nil {
+		items = append(items, m.searchView)
+	}
+	if m.hasFavorites() {
+		items = append(items, favoritesView)
+	}
+	if m.hasRecent() {
+		items = append(items, recentView)
+	}
+	folders := m.visibleFolders()
+	for _, folder := range folders {
+		items = append(items, folder)
+	}
+	if len(folders) <= 0 {
+		items = append(items, Folder(defaultSnippetFolder))
+	}
+	for _, tag := range m.allTags() {
+		items = append(items, tagView(tag))
+	}
+	if len(m.trash) > 0 {
+		items = append(items, m.trashView())
+	}
+	return items
+}
+
+// searchContents searches the content of every snippet for the pattern in
+// the search input and selects the view of the matching snippets, pinned at
+// the top of the folder pane. An empty pattern removes the view.
+func (m *Model) searchContents() {
+	pattern := m.searchInput.Value()
+	m.search = nil
+	if pattern != "" {
+		re, err := compilePattern(pattern, false, true)
+		if err != nil {
+			re, _ = compilePattern(pattern, true, true)
+		}
+		m.search = re
+		m.searchView = view{
+			name: "⌕ " + pattern,
+			match: func(s store.Snippet) bool {
+				content, err := m.store.Content(s)
+				return err == nil && re.Match(content)
+			},
+		}
+	}
+
+	m.Folders.SetItems(m.folderItems())
+	if m.search != nil {
+		m.Folders.Select(0)
+	}
+	m.refreshView()
+}
+
+// hasFavorites returns whether any snippet is a favorite.
+func (m *Model) hasFavorites() bool {
+	for _, li := range m.Lists {
+		for _, item := range li.Items() {
+			if snippet, ok := item.(store.Snippet); ok && snippet.Favorite {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allTags returns every tag used by the snippets, sorted.
+func (m *Model) allTags() []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, li := range m.Lists {
+		for _, item := range li.Items() {
+			snippet, ok := item.(store.Snippet)
+			if !ok {
+				continue
+			}
+			for _, tag := range snippet.Tags {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// completeTag completes the tag being typed in the tags input with the first
+// existing tag it is a prefix of.
+func (m *Model) completeTag() {
+	if suggestions := m.tagSuggestions(); len(suggestions) > 0 {
+		value := m.tagsInput.Value()
+		prefix := value[:len(value)-len(lastTag(value))]
+		m.tagsInput.SetValue(prefix + suggestions[0] + " ")
+		m.tagsInput.CursorEnd()
+	}
+}
+
+// tagSuggestions returns the existing tags that complete the tag being typed
+// in the tags input and that are not yet applied.
+func (m *Model) tagSuggestions() []string {
+	value := m.tagsInput.Value()
+	current := lastTag(value)
+	if current == "" {
+		return nil
+	}
+	applied := parseTags(value)
+	var suggestions []string
+	for _, tag := range m.allTags() {
+		if tag != current && strings.HasPrefix(tag, current) && !slices.Contains(applied, tag) {
+			suggestions = append(suggestions, tag)
+		}
+	}
+	return suggestions
+}
+
 // updateContentView updates the content view with the correct content based on
 // the active snippet or display the appropriate error message / hint message.
 func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
@@ -452,8 +802,13 @@ func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	snippet := store.Snippet(msg)
+	if t, ok := m.List().SelectedItem().(trashItem); ok {
+		snippet = t.Location()
+	}
+
 	var b bytes.Buffer
-	content, err := os.ReadFile(filepath.Join(m.config.Home, Snippet(msg).Path()))
+	content, err := m.store.Content(snippet)
 	if err != nil {
 		m.displayKeyHint(m.noContentHints())
 		return m, nil
@@ -471,12 +826,59 @@ func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if v, ok := m.Folders.SelectedItem().(view); ok && m.search != nil && v.name == m.searchView.name {
+		m.displayMatches(store.Snippet(msg), string(content), strings.Split(b.String(), "\n"))
+		return m, nil
+	}
+
 	s := b.String()
 	m.writeLineNumbers(lipgloss.Height(s))
 	m.Code.SetContent(s)
 	return m, nil
 }
 
+// searchContext is the number of lines of context shown around the lines
+// matching a content search.
+const searchContext = 2
+
+// displayMatches updates the content view with only the lines of the content
+// matching the content search, with the matches highlighted, surrounded by a
+// few lines of syntax highlighted context.
+func (m *Model) displayMatches(snippet store.Snippet, content string, highlighted []string) {
+	lines := strings.Split(content, "\n")
+	if len(highlighted) != len(lines) {
+		highlighted = lines
+	}
+
+	matches := map[int]store.Match{}
+	shown := map[int]bool{}
+	for _, match := range store.Grep([]byte(content), m.search) {
+		matches[match.Line-1] = match
+		for i := match.Line - 1 - searchContext; i <= match.Line-1+searchContext; i++ {
+			shown[i] = true
+		}
+	}
+
+	var code, lineNumbers strings.Builder
+	for i := range lines {
+		if !shown[i] {
+			continue
+		}
+		if i > 0 && !shown[i-1] && code.Len() > 0 {
+			lineNumbers.WriteString("  ⋮ \n")
+			code.WriteString("\n")
+		}
+		lineNumbers.WriteString(fmt.Sprintf("%3d \n", i+1))
+		if match, ok := matches[i]; ok {
+			code.WriteString(highlightMatches(match, m.ContentStyle.Match) + "\n")
+		} else {
+			code.WriteString(highlighted[i] + "\n")
+		}
+	}
+	m.LineNumbers.SetContent(lineNumbers.String() + "  ~ \n")
+	m.Code.SetContent(code.String())
+}
+
 type keyHint struct {
 	binding key.Binding
 	help    string
@@ -544,8 +946,8 @@ func (m *Model) updateActivePane(msg tea.Msg) tea.Cmd {
 		m.LineNumbers, cmd = m.LineNumbers.Update(msg)
 		cmds = append(cmds, cmd)
 	}
-	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state})
-	m.Folders.SetDelegate(folderDelegate{m.FoldersStyle})
+	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state, m.listOrder()})
+	m.Folders.SetDelegate(folderDelegate{m.FoldersStyle, m.collapsed})
 	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
 	m.Folders.Styles.Title = m.FoldersStyle.Title
 
@@ -557,36 +959,165 @@ func (m *Model) updateActivePane(msg tea.Msg) tea.Cmd {
 func (m *Model) updateKeyMap() {
 	hasItems := len(m.List().VisibleItems()) > 0
 	isFiltering := m.List().FilterState() == list.Filtering
-	isEditing := m.state == editingState
-	m.keys.DeleteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.CopySnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.PasteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.EditSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.NewSnippet.SetEnabled(!isFiltering && !isEditing)
+	isEditing := m.state == editingState || m.state == editingTagsState || m.state == searchingState || m.state == fillingTemplateState || m.state == historyState || m.state == overwritingState ||
+		m.state == editingFolderState || m.state == deletingFolderState || m.state == editingContentState || m.state == savingContentState
+	_, inView := m.Folders.SelectedItem().(view)
+	_, onFolder := m.Folders.SelectedItem().(Folder)
+	inTrash := m.inTrash()
+	inFolders := m.pane == folderPane
+	m.keys.DeleteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders)
+	m.keys.CopySnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.PasteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.EditSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.EditInline.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.TagSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.StarSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.ShowHistory.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.RestoreSnippet.SetEnabled(!isFiltering && !isEditing && (m.lastDeleted != nil || (inTrash && hasItems)))
+	m.keys.RenameSnippet.SetEnabled(!inTrash && !inFolders)
+	m.keys.SetFolder.SetEnabled(!inTrash)
+	m.keys.SetLanguage.SetEnabled(!inTrash)
+	m.keys.NewSnippet.SetEnabled(!isFiltering && !isEditing && !inView && !inFolders)
+	m.keys.MoveSnippetDown.SetEnabled(!inView && m.listOrder().manual())
+	m.keys.MoveSnippetUp.SetEnabled(!inView && m.listOrder().manual())
+	m.keys.SortSnippets.SetEnabled(!isFiltering && !isEditing && !inTrash)
+	m.keys.GroupSnippets.SetEnabled(!isFiltering && !isEditing && !inTrash)
 	m.keys.ChangeFolder.SetEnabled(m.pane == folderPane)
+	m.keys.ToggleFolder.SetEnabled(inFolders)
+	m.keys.NewFolder.SetEnabled(inFolders && !isEditing)
+	m.keys.RenameFolder.SetEnabled(inFolders && !isEditing && onFolder)
+	m.keys.MergeFolder.SetEnabled(inFolders && !isEditing && onFolder)
+	m.keys.DeleteFolder.SetEnabled(inFolders && !isEditing && onFolder)
 }
 
 // selectedSnippet returns the currently selected snippet.
-func (m *Model) selectedSnippet() Snippet {
-	item := m.List().SelectedItem()
-	if item == nil {
-		return defaultSnippet
+func (m *Model) selectedSnippet() store.Snippet {
+	switch item := m.List().SelectedItem().(type) {
+	case store.Snippet:
+		return item
+	case trashItem:
+		return item.Snippet
 	}
-	return item.(Snippet)
+	return defaultSnippet
 }
 
 // selected folder returns the currently selected folder.
 func (m *Model) selectedFolder() Folder {
-	item := m.Folders.SelectedItem()
-	if item == nil {
+	folder, ok := m.Folders.SelectedItem().(Folder)
+	if !ok {
 		return "misc"
 	}
-	return item.(Folder)
+	return folder
 }
 
-// List returns the active list.
+// List returns the active list, which is either the list of the selected
+// folder or the list of snippets gathered by the selected view. A folder
+// without a list, such as the placeholder shown once every folder is
+// deleted, gets an empty one.
 func (m *Model) List() *list.Model {
-	return m.Lists[m.selectedFolder()]
+	if v, ok := m.Folders.SelectedItem().(view); ok {
+		if m.viewList == nil || m.viewName != v.name {
+			m.refreshView()
+		}
+		return m.viewList
+	}
+	folder := m.selectedFolder()
+	li, ok := m.Lists[folder]
+	if !ok {
+		// The placeholder folder listed when there are none is empty.
+		li = newList([]list.Item{}, m.height, m.ListStyle)
+		m.Lists[folder] = li
+	}
+	return li
+}
+
+// refreshView rebuilds the list of the selected view from the snippets of
+// every folder, keeping the selected snippet if it still belongs to it.
+func (m *Model) refreshView() {
+	v, ok := m.Folders.SelectedItem().(view)
+	if !ok {
+		m.viewList, m.viewName = nil, ""
+		return
+	}
+
+	var selected list.Item
+	if m.viewList != nil && m.viewName == v.name {
+		selected = m.viewList.SelectedItem()
+	}
+
+	var items []list.Item
+	if v.items != nil {
+		items = v.items()
+	} else {
+		folders := maps.Keys(m.Lists)
+		slices.Sort(folders)
+		for _, folder := range folders {
+			for _, item := range m.Lists[folder].Items() {
+				if snippet, ok := item.(store.Snippet); ok && v.match(snippet) {
+					items = append(items, item)
+				}
+			}
+		}
+		sortItems(items, m.orderOf(v), nil)
+	}
+
+	m.viewList = newList(items, m.height, m.ListStyle)
+	m.viewName = v.name
+	for i, item := range items {
+		if sameItem(item, selected) {
+			m.viewList.Select(i)
+			break
+		}
+	}
+}
+
+// sameItem returns whether both items are the same snippet, or the same
+// deleted snippet.
+func sameItem(a, b list.Item) bool {
+	switch a := a.(type) {
+	case store.Snippet:
+		b, ok := b.(store.Snippet)
+		return ok && a.Same(b)
+	case trashItem:
+		b, ok := b.(trashItem)
+		return ok && a.ID == b.ID
+	}
+	return false
+}
+
+// folderListOf returns the list of the folder holding the snippet and the
+// index of the snippet in it.
+func (m *Model) folderListOf(snippet store.Snippet) (*list.Model, int) {
+	li, ok := m.Lists[Folder(snippet.Folder)]
+	if !ok {
+		return nil, -1
+	}
+	for i, item := range li.Items() {
+		if s, ok := item.(store.Snippet); ok && s.Same(snippet) {
+			return li, i
+		}
+	}
+	return nil, -1
+}
+
+// setSelectedSnippet replaces the selected snippet, both in the active list
+// and in the list of the folder it belongs to when a view is active.
+func (m *Model) setSelectedSnippet(snippet store.Snippet) tea.Cmd {
+	old := m.selectedSnippet()
+	cmd := m.List().SetItem(m.List().Index(), snippet)
+	if li, i := m.folderListOf(old); li != nil && li != m.List() {
+		cmd = tea.Batch(cmd, li.SetItem(i, snippet))
+	}
+	return cmd
+}
+
+// removeSelectedSnippet removes the selected snippet, both from the active
+// list and from the list of the folder it belongs to when a view is active.
+func (m *Model) removeSelectedSnippet() {
+	if li, i := m.folderListOf(m.selectedSnippet()); li != nil && li != m.List() {
+		li.RemoveItem(i)
+	}
+	m.List().RemoveItem(m.List().Index())
 }
 
 func (m *Model) moveSnippetDown() {
@@ -614,18 +1145,23 @@ func (m *Model) createNewSnippetFile() tea.Cmd {
 			folder = folderItem.FilterValue()
 		}
 
-		file := fmt.Sprintf("snippet-%d.%s", rand.Intn(1000000), m.config.DefaultLanguage)
+		id := store.NewID()
+		file := fmt.Sprintf("snippet-%s.%s", id, m.config.DefaultLanguage)
 
-		newSnippet := Snippet{
+		newSnippet := store.Snippet{
+			ID:       id,
 			Name:     defaultSnippetName,
-			Date:     time.Now(),
+			Created:  time.Now(),
+			Modified: time.Now(),
 			File:     file,
 			Language: m.config.DefaultLanguage,
 			Tags:     []string{},
 			Folder:   folder,
 		}
 
-		_, _ = os.Create(filepath.Join(m.config.Home, newSnippet.Path()))
+		if err := m.store.Create(newSnippet, nil); err != nil {
+			return errorMsg("Unable to create snippet: " + err.Error())
+		}
 
 		m.List().InsertItem(m.List().Index(), newSnippet)
 		return changeStateMsg{navigatingState}
@@ -637,26 +1173,65 @@ func (m *Model) View() string {
 	if m.state == quittingState {
 		return ""
 	}
+	if m.state == findingState {
+		return m.finderView()
+	}
+	m.foldersTitle()
 
 	var (
 		folder   = m.ContentStyle.Title.Render(m.selectedSnippet().Folder)
 		name     = m.ContentStyle.Title.Render(m.selectedSnippet().Name)
 		language = m.ContentStyle.Title.Render(m.selectedSnippet().Language)
-		titleBar = m.ListStyle.TitleBar.Render("Snippets")
+		tags     = m.ContentStyle.Tags.Render(tagsString(m.selectedSnippet().Tags))
+		titleBar = m.ListStyle.TitleBar.Render(m.listOrder().title())
 	)
 
 	if m.state == editingState {
 		folder = m.inputs[folderInput].View()
 		name = m.inputs[nameInput].View()
 		language = m.inputs[languageInput].View()
+	} else if m.state == editingTagsState {
+		tags = lipgloss.JoinHorizontal(lipgloss.Left,
+			m.ContentStyle.Tags.Render("#"),
+			m.tagsInput.View(),
+			m.ContentStyle.Tags.Render(tagsString(m.tagSuggestions())),
+		)
 	} else if m.state == copyingState {
 		titleBar = m.ListStyle.CopiedTitleBar.Render("Copied Snippet!")
+	} else if m.state == overwritingState {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Snippet Exists! (o/s/N)")
+	} else if m.state == deletingState && m.inTrash() {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Purge Snippet? (y/N)")
 	} else if m.state == deletingState {
 		titleBar = m.ListStyle.DeletedTitleBar.Render("Delete Snippet? (y/N)")
+	} else if m.lastDeleted != nil {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Moved to Trash (u to undo)")
+	} else if m.state == searchingState {
+		titleBar = m.ListStyle.TitleBar.Render(m.searchInput.View())
+	} else if m.state == historyState {
+		titleBar = m.ListStyle.TitleBar.Render(m.historyTitle())
+	} else if m.state == editingContentState || m.state == savingContentState {
+		titleBar = m.editorTitle()
 	} else if m.List().SettingFilter() {
 		titleBar = m.ListStyle.TitleBar.Render(m.List().FilterInput.View())
 	}
 
+	code := m.Code.View()
+	lineNumbers := m.LineNumbers.View()
+	if m.state == fillingTemplateState {
+		code = m.templateFormView()
+		lineNumbers = ""
+	} else if m.state == editingContentState || m.state == savingContentState {
+		code, lineNumbers = m.editor.view(m.Code.Width, m.Code.Height, m.config.Theme)
+	}
+
+	var help string
+	if m.state == editingContentState || m.state == savingContentState {
+		help = m.help.View(m.editorKeys)
+	} else {
+		help = m.help.View(m.keys)
+	}
+
 	return lipgloss.JoinVertical(
 		lipgloss.Top,
 		lipgloss.JoinHorizontal(
@@ -670,22 +1245,32 @@ func (m *Model) View() string {
 					name,
 					m.ContentStyle.Separator.Render("."),
 					language,
+					tags,
 				),
 				lipgloss.JoinHorizontal(lipgloss.Left,
-					m.ContentStyle.LineNumber.Render(m.LineNumbers.View()),
-					m.ContentStyle.Base.Render(strings.ReplaceAll(m.Code.View(), "\t", strings.Repeat(" ", tabSpaces))),
+					m.ContentStyle.LineNumber.Render(lineNumbers),
+					m.ContentStyle.Base.Render(strings.ReplaceAll(code, "\t", strings.Repeat(" ", tabSpaces))),
 				),
 			),
 		),
-		marginStyle.Render(m.help.View(m.keys)),
+		marginStyle.Render(help),
 	)
 }
 
 func (m *Model) saveState() {
+	var currentFolder string
+	if item := m.Folders.SelectedItem(); item != nil {
+		currentFolder = item.FilterValue()
+	}
 	s := State{
-		CurrentFolder:  string(m.selectedFolder()),
-		CurrentSnippet: m.selectedSnippet().File,
+		CurrentFolder:  currentFolder,
+		CurrentSnippet: m.selectedSnippet().ID,
+		Orders:         m.savedOrders(),
+	}
+	for folder := range m.collapsed {
+		s.CollapsedFolders = append(s.CollapsedFolders, string(folder))
 	}
+	slices.Sort(s.CollapsedFolders)
 	err := s.Save()
 	if err != nil {
 		panic(err.Error())
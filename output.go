@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+	"golang.org/x/exp/slices"
+)
+
+// snippetInfo is a snippet as printed by list and show, with the path of its
+// file so that scripts can open it.
+type snippetInfo struct {
+	store.Snippet
+	FilePath string `json:"path"`
+}
+
+// outputOptions select how list and show print snippets.
+type outputOptions struct {
+	format  string
+	sort    string
+	reverse bool
+	limit   int
+}
+
+// register defines the output flags on the flag set. The ordering flags are
+// only meaningful for lists.
+func (o *outputOptions) register(flags *flag.FlagSet, list bool) {
+	flags.StringVar(&o.format, "format", "", "output format: json, tsv or a Go template")
+	if !list {
+		return
+	}
+	flags.StringVar(&o.sort, "sort", "", "sort by modified, created, used, uses, name or folder")
+	flags.BoolVar(&o.reverse, "reverse", false, "reverse the order")
+	flags.IntVar(&o.limit, "limit", 0, "print at most this many snippets")
+}
+
+// order returns the snippets sorted, reversed and limited as requested.
+// Snippets are sorted newest first by date, most used first by uses, and
+// alphabetically otherwise.
+// The date sort is the modification date.
+func (o outputOptions) order(snippets []store.Snippet) ([]store.Snippet, error) {
+	snippets = slices.Clone(snippets)
+	switch o.sort {
+	case "":
+	case "modified", "date":
+		sortByDate(snippets, func(s store.Snippet) time.Time { return s.Modified })
+	case "created":
+		sortByDate(snippets, func(s store.Snippet) time.Time { return s.Created })
+	case "used":
+		sortByDate(snippets, func(s store.Snippet) time.Time { return s.LastUsed })
+	case "uses":
+		slices.SortStableFunc(snippets, func(a, b store.Snippet) int {
+			return b.Uses - a.Uses
+		})
+	case "name":
+		slices.SortStableFunc(snippets, func(a, b store.Snippet) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	case "folder":
+		slices.SortStableFunc(snippets, func(a, b store.Snippet) int {
+			if c := compareFolders(Folder(a.Folder), Folder(b.Folder)); c != 0 {
+				return c
+			}
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	default:
+		return nil, fmt.Errorf("invalid sort %q: use modified, created, used, uses, name or folder", o.sort)
+	}
+	if o.reverse {
+		slices.Reverse(snippets)
+	}
+	if o.limit > 0 && len(snippets) > o.limit {
+		snippets = snippets[:o.limit]
+	}
+	return snippets, nil
+}
+
+// sortByDate sorts the snippets newest first by the given date, keeping the
+// order of snippets with the same date.
+func sortByDate(snippets []store.Snippet, date func(store.Snippet) time.Time) {
+	slices.SortStableFunc(snippets, func(a, b store.Snippet) int {
+		switch {
+		case date(a).After(date(b)):
+			return -1
+		case date(a).Before(date(b)):
+			return 1
+		}
+		return 0
+	})
+}
+
+// formatUsed formats the date a snippet was last used in the given layout,
+// or "never" if it was never used.
+func formatUsed(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(layout)
+}
+
+// printSnippets writes the snippets to w in the requested format: their
+// folder/name.language one per line by default, a JSON array, tab separated
+// values or a Go template executed for each snippet.
+func printSnippets(w io.Writer, st *store.Store, snippets []store.Snippet, options outputOptions) error {
+	snippets, err := options.order(snippets)
+	if err != nil {
+		return err
+	}
+	infos := make([]snippetInfo, 0, len(snippets))
+	for _, snippet := range snippets {
+		infos = append(infos, snippetInfo{snippet, st.FilePath(snippet)})
+	}
+	if options.format == "json" {
+		return writeJSON(w, infos)
+	}
+	write, err := formatter(options.format)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := write(w, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatter returns the function writing a single snippet to w in the given
+// format.
+func formatter(format string) (func(w io.Writer, info snippetInfo) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, info snippetInfo) error {
+			_, err := fmt.Fprintln(w, info.Snippet)
+			return err
+		}, nil
+	case "json":
+		return func(w io.Writer, info snippetInfo) error {
+			return writeJSON(w, info)
+		}, nil
+	case "tsv":
+		return func(w io.Writer, info snippetInfo) error {
+			var used string
+			if !info.LastUsed.IsZero() {
+				used = info.LastUsed.Format(time.RFC3339)
+			}
+			_, err := fmt.Fprintln(w, strings.Join([]string{
+				info.ID,
+				tsvField(info.Folder),
+				tsvField(info.Name),
+				info.Language,
+				tsvField(strings.Join(info.Tags, ",")),
+				strconv.FormatBool(info.Favorite),
+				info.Created.Format(time.RFC3339),
+				info.Modified.Format(time.RFC3339),
+				used,
+				tsvField(info.FilePath),
+			}, "\t"))
+			return err
+		}, nil
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format: %w", err)
+	}
+	return func(w io.Writer, info snippetInfo) error {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, info); err != nil {
+			return fmt.Errorf("invalid format: %w", err)
+		}
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}, nil
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// tsvField replaces the tabs and newlines of a field, which would otherwise
+// break up the columns and rows.
+func tsvField(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maaslalani/nap/store"
+)
+
+// moveSnippet renames or moves the selected snippet from to the location of
+// to. If another snippet is already there, the user is asked whether to
+// replace it, add a suffix or cancel.
+func (m *Model) moveSnippet(from, to store.Snippet) tea.Cmd {
+	err := m.store.Move(from, to)
+	if errors.Is(err, store.ErrExists) {
+		m.moveFrom, m.moveTo = from, to
+		return changeState(overwritingState)
+	}
+	m.pane = snippetPane
+	if err != nil {
+		m.displayError("Unable to rename snippet: " + err.Error())
+		return nil
+	}
+	setCmd := m.setSelectedSnippet(to)
+	return tea.Batch(setCmd, m.updateFolders(), m.updateContent())
+}
+
+// updateOverwrite handles the key presses while asking what to do with a
+// snippet being renamed onto another one.
+func (m *Model) updateOverwrite(msg tea.KeyMsg) tea.Cmd {
+	from, to := m.moveFrom, m.moveTo
+	switch msg.String() {
+	case "o", "y":
+		replaced, err := m.store.Replace(from, to)
+		m.pane = snippetPane
+		if err != nil {
+			m.displayError("Unable to replace snippet: " + err.Error())
+			return changeState(navigatingState)
+		}
+		setCmd := m.setSelectedSnippet(to)
+		if replaced != nil {
+			m.removeReplaced(replaced.Snippet, to)
+			m.trash = append([]store.Trashed{*replaced}, m.trash...)
+		}
+		return tea.Batch(setCmd, changeState(navigatingState), m.updateFolders(), m.updateContent())
+	case "s":
+		available, err := m.store.Available(to)
+		if err != nil {
+			m.pane = snippetPane
+			m.displayError("Unable to rename snippet: " + err.Error())
+			return changeState(navigatingState)
+		}
+		// The state changes right away as the rename may run into another
+		// collision, asking again.
+		m.state = navigatingState
+		m.updateKeyMap()
+		return m.moveSnippet(from, available)
+	case "esc", "n", "c", "q":
+		m.pane = snippetPane
+		return tea.Batch(changeState(navigatingState), m.updateContent())
+	}
+	return nil
+}
+
+// removeReplaced removes the snippet replaced by a rename from its folder
+// list, keeping the renamed snippet selected.
+func (m *Model) removeReplaced(replaced, selected store.Snippet) {
+	li, i := m.folderListOf(replaced)
+	if li == nil {
+		return
+	}
+	li.RemoveItem(i)
+	if li != m.List() {
+		return
+	}
+	for i, item := range li.Items() {
+		if s, ok := item.(store.Snippet); ok && s.Same(selected) {
+			li.Select(i)
+			break
+		}
+	}
+}
+
+// displayCollision shows the snippet in the way of a rename and the choices
+// to resolve it.
+func (m *Model) displayCollision() {
+	var hints []string
+	for _, hint := range [][2]string{{"o", "overwrite"}, {"s", "add suffix"}, {"esc", "cancel"}} {
+		hints = append(hints, m.ContentStyle.EmptyHintKey.Render(hint[0])+" "+m.ContentStyle.EmptyHint.Render("• "+hint[1]))
+	}
+	m.LineNumbers.SetContent(" ~ ")
+	m.Code.SetContent(m.ContentStyle.EmptyHint.Render("A snippet already exists at "+m.moveTo.Path()+".") +
+		"\n\n" + strings.Join(hints, "   "))
+}
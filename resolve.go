@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+	"github.com/mattn/go-isatty"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/slices"
+)
+
+// Exit codes of commands which could not resolve a snippet, so that scripts
+// can tell a typo apart from other errors, and of commands given invalid
+// flags or arguments.
+const (
+	exitNotFound  = 2
+	exitAmbiguous = 3
+	exitUsage     = 64
+)
+
+var (
+	errNotFound  = errors.New("snippet not found")
+	errAmbiguous = errors.New("ambiguous snippet")
+)
+
+// maxCandidates is the number of snippets listed for ambiguous searches.
+const maxCandidates = 10
+
+// lookupError reports a search which does not match exactly one snippet,
+// with the snippets it might have meant.
+type lookupError struct {
+	search     string
+	ambiguous  bool
+	candidates []store.Snippet
+}
+
+// Error returns the message with the candidates, one per line.
+func (e *lookupError) Error() string {
+	var b strings.Builder
+	if e.ambiguous {
+		fmt.Fprintf(&b, "snippet %q is ambiguous, it matches:\n", e.search)
+	} else {
+		fmt.Fprintf(&b, "snippet %q not found", e.search)
+		if len(e.candidates) > 0 {
+			b.WriteString("\n\nDid you mean?\n")
+		}
+	}
+	for _, snippet := range e.candidates {
+		fmt.Fprintf(&b, "  %s\n", snippet)
+	}
+	if e.ambiguous {
+		b.WriteString("\nUse a full folder/name, an ID or --first to pick the best match")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Is matches errNotFound or errAmbiguous.
+func (e *lookupError) Is(target error) bool {
+	if e.ambiguous {
+		return target == errAmbiguous
+	}
+	return target == errNotFound
+}
+
+// exitCode returns the exit code of a command which failed with err.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, new(usageError)):
+		return exitUsage
+	case errors.Is(err, errNotFound):
+		return exitNotFound
+	case errors.Is(err, errAmbiguous):
+		return exitAmbiguous
+	}
+	return 1
+}
+
+// snippetLookup resolves a search to a snippet based on command line flags.
+type snippetLookup struct {
+	exact bool
+	first bool
+}
+
+// register defines the lookup flags on the flag set.
+func (l *snippetLookup) register(flags *flag.FlagSet) {
+	flags.BoolVar(&l.exact, "exact", false, "only match an ID or a full folder/name")
+	flags.BoolVar(&l.first, "first", false, "pick the best match of an ambiguous search")
+}
+
+// resolve returns the snippet with the given ID, folder/name.language or
+// folder/file. Otherwise, unless exact is set, it returns the snippet with the
+// searched name, or the best fuzzy match of the search. Searches matching
+// several snippets equally well are ambiguous: the user picks one if stdin is
+// a terminal, or the best match is returned if first is set. Equal fuzzy
+// matches are ranked by frecency, the one used most lately being returned if
+// it was used more than the others. Snippets sharing the searched name stay
+// ambiguous, listed by frecency.
+func (l snippetLookup) resolve(search string, snippets []store.Snippet) (store.Snippet, error) {
+	for _, snippet := range snippets {
+		if snippet.ID == search || snippet.String() == search || snippet.Path() == search {
+			return snippet, nil
+		}
+	}
+	if l.exact {
+		return store.Snippet{}, &lookupError{search: search, candidates: suggest(search, snippets)}
+	}
+
+	candidates := named(search, snippets)
+	fuzzyMatch := len(candidates) == 0
+	if fuzzyMatch {
+		matches := fuzzy.FindFrom(search, Snippets{snippets})
+		for _, match := range matches {
+			if match.Score < matches[0].Score {
+				break
+			}
+			candidates = append(candidates, snippets[match.Index])
+		}
+	}
+	now := time.Now()
+	slices.SortStableFunc(candidates, func(a, b store.Snippet) int {
+		return frecency(b, now) - frecency(a, now)
+	})
+	switch {
+	case len(candidates) == 0:
+		return store.Snippet{}, &lookupError{search: search, candidates: suggest(search, snippets)}
+	case len(candidates) == 1 || l.first:
+		return candidates[0], nil
+	case fuzzyMatch && frecency(candidates[0], now) > frecency(candidates[1], now):
+		return candidates[0], nil
+	case isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stderr.Fd()):
+		return chooseSnippet(os.Stderr, os.Stdin, search, candidates)
+	}
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	return store.Snippet{}, &lookupError{search: search, ambiguous: true, candidates: candidates}
+}
+
+// named returns the snippets whose name, with or without the language, is
+// the search ignoring case.
+func named(search string, snippets []store.Snippet) []store.Snippet {
+	var found []store.Snippet
+	for _, snippet := range snippets {
+		if strings.EqualFold(snippet.Name, search) || strings.EqualFold(snippet.Name+"."+snippet.Language, search) {
+			found = append(found, snippet)
+		}
+	}
+	return found
+}
+
+// chooseSnippet asks which of the candidates was meant, writing the prompt
+// to w and reading the answer from r. An empty answer picks the first one.
+func chooseSnippet(w io.Writer, r io.Reader, search string, candidates []store.Snippet) (store.Snippet, error) {
+	fmt.Fprintf(w, "%q matches several snippets:\n", search)
+	for i, snippet := range candidates {
+		fmt.Fprintf(w, "  %d) %s\n", i+1, snippet)
+	}
+	fmt.Fprint(w, "Which one? [1]: ")
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return store.Snippet{}, err
+		}
+		return store.Snippet{}, io.ErrUnexpectedEOF
+	}
+	answer := strings.TrimSpace(scanner.Text())
+	if answer == "" {
+		return candidates[0], nil
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(candidates) {
+		return store.Snippet{}, fmt.Errorf("invalid choice %q", answer)
+	}
+	return candidates[n-1], nil
+}
+
+// maxSuggestions is the number of snippets suggested for a search which
+// does not match any.
+const maxSuggestions = 3
+
+// suggest returns the snippets whose name or folder/name.language is close
+// to the search, for typos which do not even match fuzzily.
+func suggest(search string, snippets []store.Snippet) []store.Snippet {
+	type suggestion struct {
+		snippet  store.Snippet
+		distance int
+	}
+	var suggestions []suggestion
+	maxDistance := len(search) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	search = strings.ToLower(search)
+	for _, snippet := range snippets {
+		distance := levenshtein(search, strings.ToLower(snippet.Name))
+		if d := levenshtein(search, strings.ToLower(snippet.String())); d < distance {
+			distance = d
+		}
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{snippet, distance})
+		}
+	}
+	slices.SortStableFunc(suggestions, func(a, b suggestion) int {
+		return a.distance - b.distance
+	})
+
+	var found []store.Snippet
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		found = append(found, suggestions[i].snippet)
+	}
+	return found
+}
+
+// levenshtein returns the number of single character edits needed to turn a
+// into b.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			next := row[j-1] + 1
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if prev+cost < next {
+				next = prev + cost
+			}
+			prev, row[j] = row[j], next
+		}
+	}
+	return row[len(t)]
+}
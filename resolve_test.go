@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+)
+
+func TestResolve(t *testing.T) {
+	snippets := []store.Snippet{
+		{ID: "a1", Folder: "work", Name: "server", File: "server.go", Language: "go"},
+		{ID: "b2", Folder: "misc", Name: "server", File: "server.go", Language: "go", Uses: 3, LastUsed: time.Now()},
+		{ID: "c3", Folder: "misc", Name: "fizzbuzz", File: "fizzbuzz.py", Language: "py"},
+		{ID: "d4", Folder: "work", Name: "deploy", File: "deploy.sh", Language: "sh"},
+		{ID: "e5", Folder: "misc", Name: "deploy", File: "deploy.sh", Language: "sh", Uses: 1, LastUsed: time.Now()},
+		{ID: "f6", Folder: "work", Name: "release", File: "release.sh", Language: "sh"},
+		{ID: "g7", Folder: "misc", Name: "release", File: "release.sh", Language: "sh"},
+	}
+
+	tt := []struct {
+		Name   string
+		Search string
+		Lookup snippetLookup
+		Want   string
+		Err    error
+	}{
+		{Name: "id", Search: "b2", Want: "b2"},
+		{Name: "full name", Search: "work/server.go", Want: "a1"},
+		{Name: "fuzzy", Search: "fizz", Want: "c3"},
+		{Name: "exact", Search: "fizz", Lookup: snippetLookup{exact: true}, Err: errNotFound},
+		{Name: "not found", Search: "qux", Err: errNotFound},
+		{Name: "ambiguous", Search: "server", Err: errAmbiguous},
+		{Name: "first", Search: "server", Lookup: snippetLookup{first: true}, Want: "b2"},
+		{Name: "frecency", Search: "depl", Want: "e5"},
+		{Name: "fuzzy tie", Search: "relea", Err: errAmbiguous},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := tc.Lookup.resolve(tc.Search, snippets)
+			if !errors.Is(err, tc.Err) || (tc.Err == nil && err != nil) {
+				t.Logf("error is incorrect: want %v but got %v", tc.Err, err)
+				t.FailNow()
+			}
+			if got.ID != tc.Want {
+				t.Logf("snippet is incorrect: want %q but got %q", tc.Want, got.ID)
+				t.FailNow()
+			}
+		})
+	}
+
+	_, err := snippetLookup{}.resolve("sevrer", snippets)
+	if err == nil || !strings.Contains(err.Error(), "Did you mean?\n  work/server.go\n  misc/server.go") {
+		t.Logf("typo should suggest snippets: got %v", err)
+		t.FailNow()
+	}
+}
+
+func TestChooseSnippet(t *testing.T) {
+	candidates := []store.Snippet{
+		{ID: "a1", Folder: "work", Name: "server", Language: "go"},
+		{ID: "b2", Folder: "misc", Name: "server", Language: "go"},
+	}
+
+	tt := []struct {
+		Name   string
+		Answer string
+		Want   string
+		Err    bool
+	}{
+		{Name: "default", Answer: "\n", Want: "a1"},
+		{Name: "number", Answer: "2\n", Want: "b2"},
+		{Name: "invalid", Answer: "3\n", Err: true},
+		{Name: "eof", Answer: "", Err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var prompt strings.Builder
+			got, err := chooseSnippet(&prompt, strings.NewReader(tc.Answer), "server", candidates)
+			if tc.Err != (err != nil) {
+				t.Logf("error is incorrect: want error %t but got %v", tc.Err, err)
+				t.FailNow()
+			}
+			if got.ID != tc.Want {
+				t.Logf("snippet is incorrect: want %q but got %q", tc.Want, got.ID)
+				t.FailNow()
+			}
+			if !strings.Contains(prompt.String(), "  2) misc/server.go\n") {
+				t.Logf("prompt is incorrect: got %q", prompt.String())
+				t.FailNow()
+			}
+		})
+	}
+}
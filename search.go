@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/maaslalani/nap/store"
+)
+
+// compilePattern returns the regular expression for a search pattern, which
+// is taken literally if fixed is set.
+func compilePattern(pattern string, fixed, ignoreCase bool) (*regexp.Regexp, error) {
+	if fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// printMatches writes the matches to w in a <folder>/<file>:<line>:<text>
+// format, highlighting the matching text if color is set.
+func printMatches(w io.Writer, matches []store.Match, color bool, config Config) {
+	pathStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(config.PrimaryColor))
+	lineStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(config.GreenColor))
+	matchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(config.BrightRedColor)).Bold(true)
+
+	for _, match := range matches {
+		if !color {
+			fmt.Fprintf(w, "%s:%d:%s\n", match.Snippet.Path(), match.Line, match.Text)
+			continue
+		}
+		fmt.Fprintf(w, "%s:%s:%s\n",
+			pathStyle.Render(match.Snippet.Path()),
+			lineStyle.Render(fmt.Sprint(match.Line)),
+			highlightMatches(match, matchStyle),
+		)
+	}
+}
+
+// highlightMatches returns the text of the match with every matching part
+// rendered in the given style.
+func highlightMatches(match store.Match, style lipgloss.Style) string {
+	var b strings.Builder
+	var last int
+	for _, idx := range match.Indices {
+		b.WriteString(match.Text[last:idx[0]])
+		b.WriteString(style.Render(match.Text[idx[0]:idx[1]]))
+		last = idx[1]
+	}
+	b.WriteString(match.Text[last:])
+	return b.String()
+}
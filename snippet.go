@@ -2,12 +2,13 @@ package main
 
 import (
 	"bytes"
-	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/maaslalani/nap/store"
+	"golang.org/x/exp/slices"
 )
 
 // default values for empty state.
@@ -20,67 +21,70 @@ const (
 
 // defaultSnippet is a snippet with all of the default values, used for when
 // there are no snippets available.
-var defaultSnippet = Snippet{
+var defaultSnippet = store.Snippet{
 	Name:     defaultSnippetName,
 	Folder:   defaultSnippetFolder,
 	Language: defaultLanguage,
 	File:     defaultSnippetFileName,
-	Date:     time.Now(),
+	Created:  time.Now(),
+	Modified: time.Now(),
 	Tags:     make([]string, 0),
 }
 
-// Snippet represents a snippet of code in a language.
-// It is nested within a folder and can be tagged with metadata.
-type Snippet struct {
-	Tags     []string  `json:"tags"`
-	Folder   string    `json:"folder"`
-	Date     time.Time `json:"date"`
-	Favorite bool      `json:"favorite"`
-	Name     string    `json:"title"`
-	File     string    `json:"file"`
-	Language string    `json:"language"`
-}
-
-// String returns the folder/name.ext of the snippet.
-func (s Snippet) String() string {
-	return fmt.Sprintf("%s/%s.%s", s.Folder, s.Name, s.Language)
-}
-
-// LegacyPath returns the legacy path <folder>-<file>
-func (s Snippet) LegacyPath() string {
-	return s.File
+// highlightContent returns the content highlighted for the terminal in the
+// given language, or the content as is if it cannot be highlighted.
+func highlightContent(content, language, theme string) string {
+	var b bytes.Buffer
+	err := quick.Highlight(&b, content, language, "terminal16m", theme)
+	if err != nil {
+		return content
+	}
+	return b.String()
 }
 
-// Path returns the path <folder>/<file>
-func (s Snippet) Path() string {
-	return filepath.Join(s.Folder, s.File)
+// parseTags returns the tags in s, separated by whitespace or commas and
+// optionally prefixed with # or +, without duplicates.
+//
+// Example:
+//
+//	"go, #http +cli go" -> [go http cli]
+func parseTags(s string) []string {
+	tags := make([]string, 0)
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	for _, field := range fields {
+		tag := strings.TrimLeft(field, "#+")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
 
-// Content returns the snippet contents.
-func (s Snippet) Content(highlight bool) string {
-	config := readConfig()
-	file := filepath.Join(config.Home, s.Path())
-	content, err := os.ReadFile(file)
-	if err != nil {
+// lastTag returns the tag being typed at the end of s, if any.
+func lastTag(s string) string {
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ", \t") {
 		return ""
 	}
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return strings.TrimLeft(fields[len(fields)-1], "#+")
+}
 
-	if !highlight {
-		return string(content)
-	}
-
-	var b bytes.Buffer
-	err = quick.Highlight(&b, string(content), s.Language, "terminal16m", config.Theme)
-	if err != nil {
-		return string(content)
+// tagsString returns the tags formatted for display, e.g. "#go #http".
+func tagsString(tags []string) string {
+	if len(tags) == 0 {
+		return ""
 	}
-	return b.String()
+	return "#" + strings.Join(tags, " #")
 }
 
 // Snippets is a wrapper for a snippets array to implement the fuzzy.Source
 // interface.
 type Snippets struct {
-	snippets []Snippet
+	snippets []store.Snippet
 }
 
 // String returns the string of the snippet at the specified position i
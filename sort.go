@@ -0,0 +1,352 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/maaslalani/nap/store"
+	"golang.org/x/exp/slices"
+)
+
+// sortMode is the order of the snippets in a list.
+type sortMode int
+
+const (
+	// manualSort keeps the order the snippets were arranged in.
+	manualSort sortMode = iota
+	nameSort
+	modifiedSort
+	createdSort
+	usedSort
+	languageSort
+	usesSort
+)
+
+// sortModes are the names of the sort modes saved in the state, in the
+// order they are cycled through.
+var sortModes = []string{"manual", "name", "modified", "created", "used", "language", "uses"}
+
+// String returns the name of the sort mode shown in the title bar.
+func (s sortMode) String() string {
+	switch s {
+	case nameSort:
+		return "Name"
+	case modifiedSort:
+		return "Modified"
+	case createdSort:
+		return "Created"
+	case usedSort:
+		return "Last Used"
+	case languageSort:
+		return "Language"
+	case usesSort:
+		return "Most Used"
+	}
+	return "Manual"
+}
+
+// next returns the sort mode following s in the cycle.
+func (s sortMode) next() sortMode {
+	return (s + 1) % sortMode(len(sortModes))
+}
+
+// compare compares two snippets by the sort mode: alphabetically by name or
+// language, newest first by date, most used first or by their position in
+// the manual order.
+func (s sortMode) compare(a, b store.Snippet, positions map[string]int) int {
+	switch s {
+	case nameSort:
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case modifiedSort:
+		return compareDates(a.Modified, b.Modified)
+	case createdSort:
+		return compareDates(a.Created, b.Created)
+	case usedSort:
+		return compareDates(a.LastUsed, b.LastUsed)
+	case languageSort:
+		return strings.Compare(a.Language, b.Language)
+	case usesSort:
+		return b.Uses - a.Uses
+	}
+	return manualPosition(a, positions) - manualPosition(b, positions)
+}
+
+// compareDates compares two dates, the newest first.
+func compareDates(a, b time.Time) int {
+	switch {
+	case a.After(b):
+		return -1
+	case a.Before(b):
+		return 1
+	}
+	return 0
+}
+
+// groupMode is how the snippets of a list are gathered under headers.
+type groupMode int
+
+const (
+	noGroup groupMode = iota
+	languageGroup
+	tagGroup
+)
+
+// groupModes are the names of the group modes saved in the state, in the
+// order they are cycled through.
+var groupModes = []string{"", "language", "tag"}
+
+// String returns the name of the group mode shown in the title bar.
+func (g groupMode) String() string {
+	switch g {
+	case languageGroup:
+		return "Language"
+	case tagGroup:
+		return "Tag"
+	}
+	return ""
+}
+
+// next returns the group mode following g in the cycle.
+func (g groupMode) next() groupMode {
+	return (g + 1) % groupMode(len(groupModes))
+}
+
+// of returns the group of the snippet: its language, or its first tag since
+// it is listed once. Snippets without are grouped last.
+func (g groupMode) of(snippet store.Snippet) string {
+	switch g {
+	case languageGroup:
+		return snippet.Language
+	case tagGroup:
+		if len(snippet.Tags) > 0 {
+			return snippet.Tags[0]
+		}
+	}
+	return ""
+}
+
+// header returns the header of a group.
+func (g groupMode) header(group string) string {
+	if group != "" {
+		if g == tagGroup {
+			return "#" + group
+		}
+		return group
+	}
+	if g == tagGroup {
+		return "untagged"
+	}
+	return "no language"
+}
+
+// compareGroups compares two groups alphabetically, the empty one last.
+func compareGroups(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// listOrder is how the snippets of a folder or view are sorted and grouped.
+type listOrder struct {
+	sort  sortMode
+	group groupMode
+}
+
+// manual returns whether the snippets are listed in the order they were
+// arranged in, which they can be moved in.
+func (o listOrder) manual() bool {
+	return o == listOrder{}
+}
+
+// title returns the title of a list in this order.
+func (o listOrder) title() string {
+	switch {
+	case o.group == noGroup && o.sort == manualSort:
+		return "Snippets"
+	case o.group == noGroup:
+		return "Snippets by " + o.sort.String()
+	case o.sort == manualSort:
+		return "Snippets per " + o.group.String()
+	}
+	return o.sort.String() + " per " + o.group.String()
+}
+
+// ListOrder is how the snippets of a folder or view are ordered, as saved in
+// the state.
+type ListOrder struct {
+	Sort  string `json:",omitempty"`
+	Group string `json:",omitempty"`
+}
+
+// parseOrder returns the order saved in the state, ignoring unknown modes.
+func parseOrder(o ListOrder) listOrder {
+	var order listOrder
+	if i := slices.Index(sortModes, o.Sort); i >= 0 {
+		order.sort = sortMode(i)
+	}
+	if i := slices.Index(groupModes, o.Group); i >= 0 {
+		order.group = groupMode(i)
+	}
+	return order
+}
+
+// state returns the order as saved in the state.
+func (o listOrder) state() ListOrder {
+	order := ListOrder{Group: groupModes[o.group]}
+	if o.sort != manualSort {
+		order.Sort = sortModes[o.sort]
+	}
+	return order
+}
+
+// sortItems sorts the snippets of the items by group, then by the sort mode,
+// keeping the order of equal snippets. The manual order is given by the
+// positions of the snippets, those missing being placed last.
+func sortItems(items []list.Item, order listOrder, positions map[string]int) {
+	slices.SortStableFunc(items, func(a, b list.Item) int {
+		sa, aok := a.(store.Snippet)
+		sb, bok := b.(store.Snippet)
+		if !aok || !bok {
+			return 0
+		}
+		if c := compareGroups(order.group.of(sa), order.group.of(sb)); c != 0 {
+			return c
+		}
+		return order.sort.compare(sa, sb, positions)
+	})
+}
+
+// manualPosition returns the position of the snippet in the manual order.
+func manualPosition(snippet store.Snippet, positions map[string]int) int {
+	if i, ok := positions[snippet.ID]; ok {
+		return i
+	}
+	return len(positions)
+}
+
+// listOrder returns the order of the selected folder or view.
+func (m *Model) listOrder() listOrder {
+	return m.orderOf(m.Folders.SelectedItem())
+}
+
+// orderOf returns the order of the folder or view, which is the manual order
+// of folders and the order of views unless another one was picked.
+func (m *Model) orderOf(item list.Item) listOrder {
+	if item == nil {
+		return listOrder{}
+	}
+	if order, ok := m.orders[item.FilterValue()]; ok {
+		return order
+	}
+	if v, ok := item.(view); ok {
+		return v.order
+	}
+	return listOrder{}
+}
+
+// cycleSort sorts the selected folder or view by the next sort mode.
+func (m *Model) cycleSort() {
+	m.setListOrder(func(o *listOrder) {
+		o.sort = o.sort.next()
+	})
+}
+
+// cycleGroup groups the selected folder or view by the next group mode.
+func (m *Model) cycleGroup() {
+	m.setListOrder(func(o *listOrder) {
+		o.group = o.group.next()
+	})
+}
+
+// setListOrder changes the order of the selected folder or view with fn and
+// reorders it. The manual order of a folder is recorded when leaving it, to
+// be restored when cycling back to it and saved in the meantime.
+func (m *Model) setListOrder(fn func(*listOrder)) {
+	item := m.Folders.SelectedItem()
+	if item == nil {
+		return
+	}
+	order := m.orderOf(item)
+	folder, isFolder := item.(Folder)
+	if isFolder && order.manual() {
+		m.recordManualOrder(m.Lists[folder])
+	}
+	fn(&order)
+	if isFolder && order.manual() {
+		delete(m.orders, item.FilterValue())
+	} else {
+		m.orders[item.FilterValue()] = order
+	}
+	if isFolder {
+		m.sortList(folder)
+	} else {
+		m.refreshView()
+	}
+	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state, order})
+	m.updateKeyMap()
+}
+
+// recordManualOrder records the positions of the snippets of the list in
+// the manual order.
+func (m *Model) recordManualOrder(li *list.Model) {
+	if li == nil {
+		return
+	}
+	for i, item := range li.Items() {
+		if snippet, ok := item.(store.Snippet); ok {
+			m.positions[snippet.ID] = i
+		}
+	}
+}
+
+// sortList orders the list of the folder, keeping the selected snippet
+// selected.
+func (m *Model) sortList(folder Folder) {
+	li, ok := m.Lists[folder]
+	if !ok {
+		return
+	}
+	selected := li.SelectedItem()
+	items := slices.Clone(li.Items())
+	sortItems(items, m.orders[string(folder)], m.positions)
+	li.SetItems(items)
+	for i, item := range items {
+		if sameItem(item, selected) {
+			li.Select(i)
+			break
+		}
+	}
+}
+
+// manualItems returns the items of the folder in their manual order.
+func (m *Model) manualItems(folder Folder, items []list.Item) []list.Item {
+	if m.orders[string(folder)].manual() {
+		return items
+	}
+	items = slices.Clone(items)
+	sortItems(items, listOrder{}, m.positions)
+	return items
+}
+
+// savedOrders returns the orders of the folders and views to save in the
+// state, except for the content search which is not kept.
+func (m *Model) savedOrders() map[string]ListOrder {
+	orders := make(map[string]ListOrder)
+	for name, order := range m.orders {
+		if m.search != nil && name == m.searchView.name {
+			continue
+		}
+		orders[name] = order.state()
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	return orders
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/maaslalani/nap/store"
+)
+
+func TestSortItems(t *testing.T) {
+	date := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	snippets := []store.Snippet{
+		{ID: "a", Name: "beta", Language: "go", Tags: []string{"x"}, Uses: 1, Created: date, Modified: date.Add(2 * time.Hour)},
+		{ID: "b", Name: "Alpha", Language: "sh", Tags: []string{}, Uses: 3, Created: date.Add(time.Hour), Modified: date.Add(time.Hour), LastUsed: date},
+		{ID: "c", Name: "gamma", Language: "go", Tags: []string{"w", "x"}, Created: date.Add(-time.Hour), Modified: date.Add(-time.Hour)},
+	}
+
+	tt := []struct {
+		Name      string
+		Order     listOrder
+		Positions map[string]int
+		Want      string
+	}{
+		{Name: "name", Order: listOrder{sort: nameSort}, Want: "b a c"},
+		{Name: "created", Order: listOrder{sort: createdSort}, Want: "b a c"},
+		{Name: "modified", Order: listOrder{sort: modifiedSort}, Want: "a b c"},
+		{Name: "used", Order: listOrder{sort: usedSort}, Want: "b a c"},
+		{Name: "language", Order: listOrder{sort: languageSort}, Want: "a c b"},
+		{Name: "uses", Order: listOrder{sort: usesSort}, Want: "b a c"},
+		{Name: "manual", Positions: map[string]int{"c": 0, "a": 1}, Want: "c a b"},
+		{Name: "by language", Order: listOrder{sort: modifiedSort, group: languageGroup}, Want: "a c b"},
+		{Name: "by tag", Order: listOrder{group: tagGroup}, Want: "c a b"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			items := make([]list.Item, 0, len(snippets))
+			for _, snippet := range snippets {
+				items = append(items, snippet)
+			}
+			sortItems(items, tc.Order, tc.Positions)
+			var ids []string
+			for _, item := range items {
+				ids = append(ids, item.(store.Snippet).ID)
+			}
+			if got := strings.Join(ids, " "); got != tc.Want {
+				t.Logf("order is incorrect: want %q but got %q", tc.Want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestListOrderState(t *testing.T) {
+	for _, order := range []listOrder{{}, {sort: usesSort}, {group: tagGroup}, {sort: nameSort, group: languageGroup}} {
+		t.Run(order.title(), func(t *testing.T) {
+			if got := parseOrder(order.state()); got != order {
+				t.Logf("order is incorrect: want %v but got %v", order, got)
+				t.FailNow()
+			}
+		})
+	}
+}
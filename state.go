@@ -11,8 +11,15 @@ import (
 
 // State is application state between runs
 type State struct {
-	CurrentFolder  string
+	CurrentFolder string
+	// CurrentSnippet is the ID of the selected snippet, or its file name in
+	// states saved before snippets had IDs.
 	CurrentSnippet string
+	// CollapsedFolders are the folders whose nested folders are hidden.
+	CollapsedFolders []string `json:",omitempty"`
+	// Orders are how the snippets of the folders and views which are not in
+	// manual order are sorted and grouped, by name.
+	Orders map[string]ListOrder `json:",omitempty"`
 }
 
 // Save saves the state of the application
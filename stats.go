@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/slices"
+)
+
+// frecency returns how frequently and recently the snippet was used: its
+// number of uses weighted by how long ago it was last used.
+func frecency(snippet store.Snippet, now time.Time) int {
+	if snippet.Uses == 0 || snippet.LastUsed.IsZero() {
+		return 0
+	}
+	weight := 10
+	switch age := now.Sub(snippet.LastUsed); {
+	case age < 4*Day:
+		weight = 100
+	case age < 2*Week:
+		weight = 70
+	case age < Month:
+		weight = 50
+	case age < 3*Month:
+		weight = 30
+	}
+	return snippet.Uses * weight
+}
+
+// rankMatches orders the fuzzy matches of the snippets by score, and the
+// matches with the same score by frecency.
+func rankMatches(matches []fuzzy.Match, snippets []store.Snippet, now time.Time) {
+	slices.SortStableFunc(matches, func(a, b fuzzy.Match) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return frecency(snippets[b.Index], now) - frecency(snippets[a.Index], now)
+	})
+}
+
+// recentAge is how long snippets stay in the recent view after being used.
+const recentAge = Month
+
+// recentView is a view of the snippets used lately, the last used first.
+var recentView = view{
+	name: "◷ Recent",
+	match: func(s store.Snippet) bool {
+		return !s.LastUsed.IsZero() && time.Since(s.LastUsed) < recentAge
+	},
+	order: listOrder{sort: usedSort},
+}
+
+// hasRecent returns whether any snippet was used lately.
+func (m *Model) hasRecent() bool {
+	for _, li := range m.Lists {
+		for _, item := range li.Items() {
+			if snippet, ok := item.(store.Snippet); ok && recentView.match(snippet) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runStats prints the most and least used snippets, with how many times and
+// when they were last used, followed by the snippets never used. The
+// snippets can be filtered by tag or favorites.
+func runStats(w io.Writer, st *store.Store, config Config, snippets []store.Snippet, args []string) error {
+	var filter snippetFilter
+	var limit int
+	flags := newFlagSet("stats")
+	filter.register(flags)
+	flags.IntVar(&limit, "limit", 5, "number of most and least used snippets to print")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return errUsage("stats [--tag <tag>] [--favorites] [--limit N]")
+	}
+	snippets = filter.apply(snippets)
+
+	var used, never []store.Snippet
+	for _, snippet := range snippets {
+		if snippet.Uses > 0 {
+			used = append(used, snippet)
+		} else {
+			never = append(never, snippet)
+		}
+	}
+	slices.SortStableFunc(used, func(a, b store.Snippet) int {
+		if a.Uses != b.Uses {
+			return b.Uses - a.Uses
+		}
+		return compareDates(a.LastUsed, b.LastUsed)
+	})
+
+	most := used
+	if limit > 0 && len(most) > limit {
+		most = most[:limit]
+	}
+	least := slices.Clone(used[len(most):])
+	slices.Reverse(least)
+	if limit > 0 && len(least) > limit {
+		least = least[:limit]
+	}
+
+	fmt.Fprintf(w, "%d snippets, %d used, %d never used\n", len(snippets), len(used), len(never))
+	printUses(w, "Most used", most)
+	printUses(w, "Least used", least)
+	if len(never) > 0 {
+		fmt.Fprintln(w, "\nNever used:")
+		for _, snippet := range never {
+			fmt.Fprintf(w, "  %s\n", snippet)
+		}
+	}
+	return nil
+}
+
+// printUses prints the snippets under a heading with their number of uses
+// and when they were last used, unless there are none.
+func printUses(w io.Writer, heading string, snippets []store.Snippet) {
+	if len(snippets) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", heading)
+	for _, snippet := range snippets {
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", snippet.Uses, humanizeTime(snippet.LastUsed), snippet)
+	}
+}
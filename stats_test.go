@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maaslalani/nap/store"
+	"github.com/sahilm/fuzzy"
+)
+
+func TestRankMatches(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	snippets := []store.Snippet{
+		{ID: "a", Uses: 20, LastUsed: now.AddDate(0, -6, 0)},
+		{ID: "b", Uses: 3, LastUsed: now.Add(-time.Hour)},
+		{ID: "c"},
+		{ID: "d", Uses: 1, LastUsed: now},
+	}
+	matches := []fuzzy.Match{
+		{Index: 0, Score: 5},
+		{Index: 2, Score: 10},
+		{Index: 1, Score: 5},
+		{Index: 3, Score: 5},
+	}
+
+	rankMatches(matches, snippets, now)
+	var ids []string
+	for _, match := range matches {
+		ids = append(ids, snippets[match.Index].ID)
+	}
+	if got, want := strings.Join(ids, " "), "c b a d"; got != want {
+		t.Logf("ranking is incorrect: want %q but got %q", want, got)
+		t.FailNow()
+	}
+}
+
+func TestStats(t *testing.T) {
+	st := store.New(t.TempDir(), "snippets.json")
+	now := time.Now()
+	snippets := []store.Snippet{
+		{Folder: "misc", Name: "a", Language: "sh", Tags: []string{"go"}, Uses: 5, LastUsed: now},
+		{Folder: "misc", Name: "b", Language: "sh", Tags: []string{"go"}, Uses: 1, LastUsed: now.Add(-time.Hour)},
+		{Folder: "misc", Name: "c", Language: "sh"},
+		{Folder: "misc", Name: "d", Language: "sh", Uses: 2, LastUsed: now},
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{
+			Name: "default",
+			Want: "4 snippets, 3 used, 1 never used\n\nMost used:\n  5\tjust now\tmisc/a.sh\n  2\tjust now\tmisc/d.sh\n  1\t1h ago\tmisc/b.sh\n\nNever used:\n  misc/c.sh\n",
+		},
+		{
+			Name: "limit",
+			Args: []string{"--limit", "1"},
+			Want: "4 snippets, 3 used, 1 never used\n\nMost used:\n  5\tjust now\tmisc/a.sh\n\nLeast used:\n  1\t1h ago\tmisc/b.sh\n\nNever used:\n  misc/c.sh\n",
+		},
+		{
+			Name: "tag",
+			Args: []string{"--tag", "go"},
+			Want: "2 snippets, 2 used, 0 never used\n\nMost used:\n  5\tjust now\tmisc/a.sh\n  1\t1h ago\tmisc/b.sh\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var b strings.Builder
+			if err := runStats(&b, st, Config{}, snippets, tc.Args); err != nil {
+				t.Logf("could not print stats: %v", err)
+				t.FailNow()
+			}
+			if b.String() != tc.Want {
+				t.Logf("stats are incorrect: want %q but got %q", tc.Want, b.String())
+				t.FailNow()
+			}
+		})
+	}
+}
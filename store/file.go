@@ -0,0 +1,64 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempSuffix is the suffix of the temporary files used for atomic writes.
+const tempSuffix = ".tmp"
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so that readers never observe a partially written file even if
+// the process crashes halfway through.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// isTempFile reports whether name is a temporary file left behind by
+// writeFileAtomic.
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, tempSuffix)
+}
+
+// lock acquires an exclusive advisory lock guarding read-modify-write cycles
+// of the metadata file across processes. The returned function releases it.
+func (s *Store) lock() (func(), error) {
+	if err := os.MkdirAll(s.home, os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(s.home, "."+s.file+".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
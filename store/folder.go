@@ -0,0 +1,248 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoFolder is returned when a folder does not exist.
+	ErrNoFolder = errors.New("folder not found")
+	// ErrFolderExists is returned when an operation would replace a folder.
+	ErrFolderExists = errors.New("folder already exists")
+)
+
+// folderPath returns the absolute path of the folder.
+func (s *Store) folderPath(folder string) string {
+	return filepath.Join(s.home, filepath.FromSlash(folder))
+}
+
+// Folders returns every folder in home, including the empty and the nested
+// ones, in lexical order.
+func (s *Store) Folders() ([]string, error) {
+	var folders []string
+	err := filepath.WalkDir(s.home, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == s.home {
+				return err
+			}
+			return nil
+		}
+		if !entry.IsDir() || path == s.home {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		folder, err := filepath.Rel(s.home, path)
+		if err != nil {
+			return err
+		}
+		folders = append(folders, filepath.ToSlash(folder))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return folders, err
+}
+
+// CreateFolder creates an empty folder.
+func (s *Store) CreateFolder(folder string) error {
+	if err := validFolder(folder); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.folderPath(folder)); err == nil {
+		return fmt.Errorf("%w: %s", ErrFolderExists, folder)
+	}
+	return os.MkdirAll(s.folderPath(folder), os.ModePerm)
+}
+
+// RenameFolder renames the folder along with its nested folders, moving the
+// files of its snippets. Renaming onto an existing folder
+// fails with ErrFolderExists, see MergeFolder.
+func (s *Store) RenameFolder(from, to string) error {
+	if err := s.checkFolderMove(from, to); err != nil || from == to {
+		return err
+	}
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if !s.folderExists(snippets, from) {
+			return nil, fmt.Errorf("%w: %s", ErrNoFolder, from)
+		}
+		if s.folderExists(snippets, to) {
+			return nil, fmt.Errorf("%w: %s", ErrFolderExists, to)
+		}
+		if err := os.MkdirAll(filepath.Dir(s.folderPath(to)), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(s.folderPath(from), s.folderPath(to)); err != nil {
+			return nil, err
+		}
+
+		for i, snippet := range snippets {
+			if snippet.InFolder(from) {
+				snippets[i].Folder = to + strings.TrimPrefix(snippet.Folder, from)
+			}
+		}
+		return snippets, nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit(fmt.Sprintf("Rename folder %s to %s", from, to))
+}
+
+// MergeFolder moves the snippets and nested folders of the folder into
+// another one, which is created if needed, and removes the folder. Nothing
+// is moved if a snippet would replace another one, which fails with
+// ErrExists.
+func (s *Store) MergeFolder(from, into string) error {
+	if err := s.checkFolderMove(from, into); err != nil || from == into {
+		return err
+	}
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if !s.folderExists(snippets, from) {
+			return nil, fmt.Errorf("%w: %s", ErrNoFolder, from)
+		}
+		moved := make(map[int]Snippet)
+		for i, snippet := range snippets {
+			if !snippet.InFolder(from) {
+				continue
+			}
+			to := snippet
+			to.Folder = into + strings.TrimPrefix(snippet.Folder, from)
+			if s.taken(snippets, to.Path()) {
+				return nil, fmt.Errorf("%w: %s", ErrExists, to.Path())
+			}
+			moved[i] = to
+		}
+
+		for i, to := range moved {
+			from := snippets[i]
+			if err := os.MkdirAll(filepath.Dir(s.FilePath(to)), os.ModePerm); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(s.FilePath(from), s.FilePath(to)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			snippets[i] = to
+		}
+
+		// The empty nested folders are kept, then the folder is removed from
+		// the deepest folder up, leaving any folder which is not empty.
+		folders, err := s.Folders()
+		if err != nil {
+			return nil, err
+		}
+		var removed []string
+		for _, folder := range folders {
+			if folder != from && !nested(folder, from) {
+				continue
+			}
+			if err := os.MkdirAll(s.folderPath(into+strings.TrimPrefix(folder, from)), os.ModePerm); err != nil {
+				return nil, err
+			}
+			removed = append(removed, folder)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(removed)))
+		for _, folder := range removed {
+			_ = os.Remove(s.folderPath(folder))
+		}
+		return snippets, nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit(fmt.Sprintf("Merge folder %s into %s", from, into))
+}
+
+// DeleteFolder moves every snippet of the folder and its nested folders to
+// the trash at once and removes the folders left empty, keeping those holding
+// files which are not snippets. Nothing is deleted if a snippet cannot be
+// moved to the trash. The deleted snippets are returned.
+func (s *Store) DeleteFolder(folder string) ([]Trashed, error) {
+	var trash []Trashed
+	deleted := time.Now()
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if !s.folderExists(snippets, folder) {
+			return nil, fmt.Errorf("%w: %s", ErrNoFolder, folder)
+		}
+		kept := snippets[:0]
+		for _, snippet := range snippets {
+			if !snippet.InFolder(folder) {
+				kept = append(kept, snippet)
+				continue
+			}
+			if err := s.Record(snippet); err != nil {
+				s.takeOutOfTrash(trash)
+				return nil, fmt.Errorf("unable to record history: %w", err)
+			}
+			trashed, err := s.moveToTrash(snippet, deleted)
+			if err != nil {
+				s.takeOutOfTrash(trash)
+				return nil, fmt.Errorf("unable to delete %s: %w", snippet, err)
+			}
+			trash = append(trash, trashed)
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := s.Folders()
+	if err != nil {
+		return trash, err
+	}
+	var removed []string
+	for _, f := range folders {
+		if f == folder || nested(f, folder) {
+			removed = append(removed, f)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(removed)))
+	for _, f := range removed {
+		_ = os.Remove(s.folderPath(f))
+	}
+	return trash, s.Commit("Delete folder " + folder)
+}
+
+// checkFolderMove returns an error if the folder cannot be moved to the
+// other one, such as into one of its own nested folders.
+func (s *Store) checkFolderMove(from, to string) error {
+	if err := validFolder(from); err != nil {
+		return err
+	}
+	if err := validFolder(to); err != nil {
+		return err
+	}
+	if nested(to, from) {
+		return fmt.Errorf("%w: cannot move %s into itself", ErrInvalidName, from)
+	}
+	return nil
+}
+
+// folderExists returns whether the folder exists on disk or holds any of the
+// snippets.
+func (s *Store) folderExists(snippets []Snippet, folder string) bool {
+	if info, err := os.Stat(s.folderPath(folder)); err == nil && info.IsDir() {
+		return true
+	}
+	for _, snippet := range snippets {
+		if snippet.InFolder(folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// nested returns whether the folder is nested in the parent, at any depth.
+func nested(folder, parent string) bool {
+	return strings.HasPrefix(folder, parent+"/")
+}
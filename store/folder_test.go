@@ -0,0 +1,105 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestFolders(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	for _, snippet := range []Snippet{
+		{Folder: "work/go", Name: "a", File: "a.go", Language: "go"},
+		{Folder: "work", Name: "b", File: "b.go", Language: "go"},
+		{Folder: "misc", Name: "b", File: "b.go", Language: "go"},
+	} {
+		create(t, st, snippet)
+	}
+	if err := st.CreateFolder("work/empty"); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := st.CreateFolder("work"); !errors.Is(err, ErrFolderExists) {
+		t.Logf("creating an existing folder should fail: %v", err)
+		t.FailNow()
+	}
+
+	paths := func() []string {
+		snippets, err := st.Load()
+		if err != nil {
+			t.Logf("could not load snippets: %v", err)
+			t.FailNow()
+		}
+		var paths []string
+		for _, snippet := range snippets {
+			paths = append(paths, snippet.Path())
+		}
+		slices.Sort(paths)
+		return paths
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		if got := paths(); !slices.Equal(got, want) {
+			t.Logf("snippets are incorrect: want %v but got %v", want, got)
+			t.FailNow()
+		}
+	}
+
+	if err := st.RenameFolder("work", "job"); err != nil {
+		t.Logf("could not rename folder: %v", err)
+		t.FailNow()
+	}
+	expect("job/b.go", "job/go/a.go", "misc/b.go")
+	if folders, _ := st.Folders(); !slices.Contains(folders, "job/empty") {
+		t.Logf("empty folder was not renamed: %v", folders)
+		t.FailNow()
+	}
+	if err := st.RenameFolder("job", "misc"); !errors.Is(err, ErrFolderExists) {
+		t.Logf("renaming onto an existing folder should fail: %v", err)
+		t.FailNow()
+	}
+	if err := st.RenameFolder("job", "job/go"); !errors.Is(err, ErrInvalidName) {
+		t.Logf("renaming into a nested folder should fail: %v", err)
+		t.FailNow()
+	}
+
+	if err := st.MergeFolder("job", "misc"); !errors.Is(err, ErrExists) {
+		t.Logf("merging onto another snippet should fail: %v", err)
+		t.FailNow()
+	}
+	expect("job/b.go", "job/go/a.go", "misc/b.go")
+	if err := st.MergeFolder("job/go", "misc"); err != nil {
+		t.Logf("could not merge folder: %v", err)
+		t.FailNow()
+	}
+	expect("job/b.go", "misc/a.go", "misc/b.go")
+
+	trashed, err := st.DeleteFolder("job")
+	if err != nil || len(trashed) != 1 {
+		t.Logf("could not delete folder: %v (%v)", trashed, err)
+		t.FailNow()
+	}
+	expect("misc/a.go", "misc/b.go")
+	if folders, _ := st.Folders(); !slices.Equal(folders, []string{"misc"}) {
+		t.Logf("folders are incorrect: want [misc] but got %v", folders)
+		t.FailNow()
+	}
+
+	create(t, st, Snippet{Folder: "old/go", Name: "c", File: "c.go", Language: "go"})
+	untracked := filepath.Join(st.folderPath("old/go"), "notes.txt")
+	if err := os.WriteFile(untracked, []byte("notes"), 0o644); err != nil {
+		t.Logf("could not write file: %v", err)
+		t.FailNow()
+	}
+	if trashed, err := st.DeleteFolder("old"); err != nil || len(trashed) != 1 {
+		t.Logf("could not delete folder: %v (%v)", trashed, err)
+		t.FailNow()
+	}
+	if content, err := os.ReadFile(untracked); err != nil || string(content) != "notes" {
+		t.Logf("files which are not snippets should be kept: %q (%v)", content, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,289 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// ErrConflict is returned when syncing runs into conflicting changes to the
+// content of snippets, which need to be resolved by hand.
+var ErrConflict = errors.New("conflicting changes")
+
+// gitIgnore lists the files of home which are local to each machine: the
+// revisions, the trash and the files used while writing.
+var gitIgnore = strings.Join([]string{
+	historyDir + "/",
+	trashDir + "/",
+	".*.lock",
+	".*" + tempSuffix,
+}, "\n") + "\n"
+
+// git runs a git command in the home directory and returns its output.
+func (s *Store) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", s.home}, args...)...)
+	cmd.Env = append(os.Environ(), s.gitEnv...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitIdentity returns the configured value of the git identity key, or the
+// fallback if there is none.
+func (s *Store) gitIdentity(key, fallback string) string {
+	out, err := exec.Command("git", "-C", s.home, "config", "--get", key).Output()
+	if value := strings.TrimSpace(string(out)); err == nil && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// EnableGit turns on git mode, in which every change made through the store
+// is committed. The home directory is made a git repository if it is not one
+// already, and the files local to each machine are ignored in either case.
+func (s *Store) EnableGit() error {
+	if err := os.MkdirAll(s.home, os.ModePerm); err != nil {
+		return err
+	}
+	s.gitMode = true
+	// Commits are made on behalf of nap if no identity is configured.
+	name, email := s.gitIdentity("user.name", "nap"), s.gitIdentity("user.email", "nap@localhost")
+	s.gitEnv = []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + email,
+		"GIT_COMMITTER_NAME=" + name,
+		"GIT_COMMITTER_EMAIL=" + email,
+		"GIT_EDITOR=true",
+	}
+	if _, err := os.Stat(filepath.Join(s.home, ".git")); err == nil {
+		return s.excludeLocalFiles()
+	}
+	if _, err := s.git("init", "--quiet"); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.home, ".gitignore"), []byte(gitIgnore), 0o644); err != nil {
+		return err
+	}
+	if err := s.excludeLocalFiles(); err != nil {
+		return err
+	}
+	return s.Commit("Initialize snippets")
+}
+
+// excludeLocalFiles adds the entries of gitIgnore missing from the exclude
+// file of the repository, so that the files local to each machine are not
+// committed in repositories whose .gitignore was not written by nap.
+func (s *Store) excludeLocalFiles() error {
+	path, err := s.git("rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.home, path)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	lines := strings.Split(string(b), "\n")
+	exclude := string(b)
+	if exclude != "" && !strings.HasSuffix(exclude, "\n") {
+		exclude += "\n"
+	}
+	missing := false
+	for _, entry := range strings.Split(strings.TrimSpace(gitIgnore), "\n") {
+		if !slices.Contains(lines, entry) {
+			exclude += entry + "\n"
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(exclude), 0o644)
+}
+
+// Commit commits every change in the home directory with the given message
+// when git mode is enabled. Nothing is committed if nothing changed.
+func (s *Store) Commit(message string) error {
+	if !s.gitMode {
+		return nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.commit(message)
+}
+
+// commit commits every change in the home directory. The caller must hold
+// the lock.
+func (s *Store) commit(message string) error {
+	if _, err := s.git("add", "--all"); err != nil {
+		return err
+	}
+	if _, err := s.git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	_, err := s.git("commit", "--quiet", "--message", message)
+	return err
+}
+
+// Sync commits any pending change, rebases the local commits onto the
+// changes of the remote and pushes the result. The remote is either the name
+// of a configured remote or a URL, which is configured as origin.
+//
+// Conflicting changes to the metadata file are resolved by merging the
+// snippets with Merge, the local changes winning. Conflicting changes to the
+// content of a snippet abort the sync with ErrConflict.
+func (s *Store) Sync(remote string) error {
+	if !s.gitMode {
+		return errors.New("git mode is disabled")
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.commit("Sync snippets"); err != nil {
+		return err
+	}
+	remote, err = s.gitRemote(remote)
+	if err != nil {
+		return err
+	}
+	branch, err := s.git("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+	if _, err := s.git("fetch", "--quiet", remote); err != nil {
+		return err
+	}
+
+	upstream := remote + "/" + branch
+	if _, err := s.git("rev-parse", "--verify", "--quiet", upstream); err == nil {
+		if _, err := s.git("rebase", "--quiet", upstream); err != nil {
+			if err := s.resolveRebase(); err != nil {
+				_, _ = s.git("rebase", "--abort")
+				return err
+			}
+		}
+	}
+	_, err = s.git("push", "--quiet", "--set-upstream", remote, branch)
+	return err
+}
+
+// gitRemote returns the name of the remote, configuring a URL as origin.
+func (s *Store) gitRemote(remote string) (string, error) {
+	if remote == "" {
+		remote = "origin"
+	}
+	remotes, err := s.git("remote")
+	if err != nil {
+		return "", err
+	}
+	for _, name := range strings.Fields(remotes) {
+		if name == remote {
+			return remote, nil
+		}
+	}
+	if url, err := s.git("remote", "get-url", "origin"); err == nil {
+		if url == remote {
+			return "origin", nil
+		}
+		_, err := s.git("remote", "set-url", "origin", remote)
+		return "origin", err
+	}
+	_, err = s.git("remote", "add", "origin", remote)
+	return "origin", err
+}
+
+// resolveRebase resolves the conflicts of a stopped rebase in the metadata
+// file and continues it, until the rebase is done.
+func (s *Store) resolveRebase() error {
+	for s.rebasing() {
+		conflicts, err := s.git("diff", "--name-only", "--diff-filter=U")
+		if err != nil {
+			return err
+		}
+		if conflicts == "" {
+			// The rebase stopped for another reason, such as a local commit
+			// which became empty once its conflicts were resolved.
+			if _, err := s.git("rebase", "--skip"); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, path := range strings.Fields(conflicts) {
+			if path != s.file {
+				return fmt.Errorf("%w in %s, resolve them with git in %s", ErrConflict, path, s.home)
+			}
+		}
+		if err := s.resolveMetadata(); err != nil {
+			return err
+		}
+		_, _ = s.git("rebase", "--continue")
+	}
+	return nil
+}
+
+// rebasing returns whether a rebase is in progress.
+func (s *Store) rebasing() bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		path, err := s.git("rev-parse", "--git-path", dir)
+		if err != nil {
+			return false
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.home, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMetadata resolves a conflict in the metadata file by merging the
+// snippets of both sides. While rebasing, the upstream changes are in stage 2
+// and the local changes being replayed in stage 3.
+func (s *Store) resolveMetadata() error {
+	var stages [3][]Snippet
+	for i := range stages {
+		out, err := s.git("show", fmt.Sprintf(":%d:%s", i+1, s.file))
+		if err != nil {
+			// The file was added on both sides, without a common ancestor.
+			continue
+		}
+		if err := json.Unmarshal([]byte(out), &stages[i]); err != nil {
+			return fmt.Errorf("unable to unmarshal %s: %w", s.file, err)
+		}
+	}
+	base, theirs, ours := stages[0], stages[1], stages[2]
+	if err := s.save(Merge(base, ours, theirs)); err != nil {
+		return err
+	}
+	_, err := s.git("add", s.file)
+	return err
+}
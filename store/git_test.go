@@ -0,0 +1,153 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestGitSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmp := t.TempDir()
+	remote := filepath.Join(tmp, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Logf("could not create remote: %v: %s", err, out)
+		t.FailNow()
+	}
+
+	a := New(filepath.Join(tmp, "a"), "snippets.json")
+	if err := a.EnableGit(); err != nil {
+		t.Logf("could not enable git: %v", err)
+		t.FailNow()
+	}
+	create(t, a, Snippet{Folder: "foo", Name: "a", File: "a.go", Language: "go"})
+	if err := a.Sync(remote); err != nil {
+		t.Logf("could not sync: %v", err)
+		t.FailNow()
+	}
+
+	if out, err := exec.Command("git", "clone", "--quiet", remote, filepath.Join(tmp, "b")).CombinedOutput(); err != nil {
+		t.Logf("could not clone remote: %v: %s", err, out)
+		t.FailNow()
+	}
+	b := New(filepath.Join(tmp, "b"), "snippets.json")
+	if err := b.EnableGit(); err != nil {
+		t.Logf("could not enable git: %v", err)
+		t.FailNow()
+	}
+
+	// Both sides add a snippet, which conflicts in the metadata file.
+	create(t, b, Snippet{Folder: "foo", Name: "b", File: "b.go", Language: "go"})
+	if err := b.Sync(""); err != nil {
+		t.Logf("could not sync: %v", err)
+		t.FailNow()
+	}
+	create(t, a, Snippet{Folder: "foo", Name: "c", File: "c.go", Language: "go"})
+	if err := a.Sync(remote); err != nil {
+		t.Logf("could not sync: %v", err)
+		t.FailNow()
+	}
+
+	snippets, err := a.Load()
+	if err != nil {
+		t.Logf("could not load snippets: %v", err)
+		t.FailNow()
+	}
+	var got []string
+	for _, snippet := range snippets {
+		got = append(got, snippet.Name)
+	}
+	if want := []string{"b", "c", "a"}; !slices.Equal(got, want) {
+		t.Logf("merged snippets are incorrect: want %v but got %v", want, got)
+		t.FailNow()
+	}
+
+	log, err := a.git("log", "--format=%s")
+	if err != nil {
+		t.Logf("could not read log: %v", err)
+		t.FailNow()
+	}
+	want := "Add foo/c.go\nAdd foo/b.go\nAdd foo/a.go\nInitialize snippets"
+	if log != want {
+		t.Logf("commits are incorrect: want %q but got %q", want, log)
+		t.FailNow()
+	}
+	if status, _ := a.git("status", "--porcelain"); status != "" {
+		t.Logf("working tree is not clean: %s", strings.TrimSpace(status))
+		t.FailNow()
+	}
+}
+
+// create creates an empty snippet or fails the test.
+func TestGitExistingRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	home := t.TempDir()
+	if out, err := exec.Command("git", "init", "--quiet", home).CombinedOutput(); err != nil {
+		t.Logf("could not create repository: %v: %s", err, out)
+		t.FailNow()
+	}
+	st := New(home, "snippets.json")
+	if err := st.EnableGit(); err != nil {
+		t.Logf("could not enable git: %v", err)
+		t.FailNow()
+	}
+	snippet := Snippet{ID: NewID(), Folder: "foo", Name: "a", File: "a.go", Language: "go"}
+	create(t, st, snippet)
+	if err := st.Write(snippet, []byte("package a")); err != nil {
+		t.Logf("could not write snippet: %v", err)
+		t.FailNow()
+	}
+	if _, err := st.Trash(snippet); err != nil {
+		t.Logf("could not trash snippet: %v", err)
+		t.FailNow()
+	}
+
+	out, err := st.git("ls-files")
+	if err != nil {
+		t.Logf("could not list files: %v", err)
+		t.FailNow()
+	}
+	if strings.Contains(out, historyDir) || strings.Contains(out, trashDir) {
+		t.Logf("local files should not be committed: got %q", out)
+		t.FailNow()
+	}
+	if err := st.EnableGit(); err != nil {
+		t.Logf("could not enable git again: %v", err)
+		t.FailNow()
+	}
+	exclude, _ := os.ReadFile(filepath.Join(home, ".git", "info", "exclude"))
+	if n := strings.Count(string(exclude), trashDir+"/"); n != 1 {
+		t.Logf("exclude file should list the trash once: got %q", exclude)
+		t.FailNow()
+	}
+
+	create(t, st, Snippet{Folder: "bar", Name: "b", File: "b.go", Language: "go"})
+	create(t, st, Snippet{Folder: "bar/baz", Name: "c", File: "c.go", Language: "go"})
+	if trashed, err := st.DeleteFolder("bar"); err != nil || len(trashed) != 2 {
+		t.Logf("could not delete folder: %v (%v)", trashed, err)
+		t.FailNow()
+	}
+	if log, _ := st.git("log", "--format=%s", "-n", "2"); log != "Delete folder bar\nAdd bar/baz/c.go" {
+		t.Logf("deleting a folder should make one commit: got %q", log)
+		t.FailNow()
+	}
+}
+
+func create(t *testing.T, s *Store, snippet Snippet) {
+	t.Helper()
+	if err := s.Create(snippet, []byte(fmt.Sprintf("package %s", snippet.Name))); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// historyDir is the hidden directory of home keeping the revisions of every
+// snippet. Hidden directories are ignored when scanning for snippets.
+const historyDir = ".history"
+
+// ErrNoRevision is returned when a snippet has no revision with the given
+// number.
+var ErrNoRevision = errors.New("revision not found")
+
+// Revision is a recorded version of the content of a snippet. Revisions are
+// numbered from 1, oldest first.
+type Revision struct {
+	Rev  int
+	Date time.Time
+	Size int64
+}
+
+// historyPath returns the directory keeping the revisions of the snippet,
+// named after its ID so that the history follows the snippet when it is
+// moved and is not inherited by another snippet at its former location. The
+// ID of a snippet given by location is looked up in the metadata.
+func (s *Store) historyPath(snippet Snippet) string {
+	if snippet.ID == "" {
+		if stored, err := s.Get(snippet.Path()); err == nil {
+			snippet.ID = stored.ID
+		}
+	}
+	if snippet.ID == "" {
+		return s.legacyHistoryPath(snippet)
+	}
+	return filepath.Join(s.home, historyDir, snippet.ID)
+}
+
+// legacyHistoryPath returns the directory which kept the revisions of the
+// snippet when they were named after its location.
+func (s *Store) legacyHistoryPath(snippet Snippet) string {
+	return filepath.Join(s.home, historyDir, filepath.FromSlash(snippet.Path()))
+}
+
+// revisionPath returns the file of the given revision of the snippet.
+func (s *Store) revisionPath(snippet Snippet, rev int) string {
+	return filepath.Join(s.historyPath(snippet), strconv.Itoa(rev))
+}
+
+// History returns the revisions of the snippet, oldest first.
+func (s *Store) History(snippet Snippet) ([]Revision, error) {
+	entries, err := os.ReadDir(s.historyPath(snippet))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	for _, entry := range entries {
+		rev, err := strconv.Atoi(entry.Name())
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, Revision{Rev: rev, Date: info.ModTime(), Size: info.Size()})
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Rev < revisions[j].Rev
+	})
+	return revisions, nil
+}
+
+// Revision returns the content of the given revision of the snippet.
+func (s *Store) Revision(snippet Snippet, rev int) ([]byte, error) {
+	content, err := os.ReadFile(s.revisionPath(snippet, rev))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s@%d", ErrNoRevision, snippet.Path(), rev)
+	}
+	return content, err
+}
+
+// Record records the current content of the snippet file as a new revision,
+// unless it is the same as the latest one. It is called before and after the
+// store changes a snippet, and should be called after the file was changed
+// by other means, such as an editor.
+func (s *Store) Record(snippet Snippet) error {
+	content, err := s.Content(snippet)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.record(snippet, content)
+}
+
+// record records content as a new revision of the snippet unless it is the
+// same as the latest one.
+func (s *Store) record(snippet Snippet, content []byte) error {
+	revisions, err := s.History(snippet)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 && len(content) == 0 {
+		return nil
+	}
+	next := 1
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		if b, err := s.Revision(snippet, latest.Rev); err == nil && bytes.Equal(b, content) {
+			return nil
+		}
+		next = latest.Rev + 1
+	}
+
+	dir := s.historyPath(snippet)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create history: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Linking fails if another process recorded the same revision number in
+	// the meantime, in which case the next free number is used.
+	for {
+		err := os.Link(tmp, s.revisionPath(snippet, next))
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		next++
+	}
+}
+
+// Restore replaces the content of the snippet with the given revision, which
+// records it as the latest revision.
+func (s *Store) Restore(snippet Snippet, rev int) error {
+	content, err := s.Revision(snippet, rev)
+	if err != nil {
+		return err
+	}
+	if err := s.write(snippet, content); err != nil {
+		return err
+	}
+	if _, err := s.touch(snippet, modified(time.Now())); err != nil {
+		return err
+	}
+	return s.Commit(fmt.Sprintf("Restore %s to revision %d", snippet, rev))
+}
+
+// migrateHistory moves the revisions of the snippet kept at its location to
+// the directory named after its ID, unless it already has one.
+func (s *Store) migrateHistory(snippet Snippet) error {
+	legacyPath, newPath := s.legacyHistoryPath(snippet), s.historyPath(snippet)
+	if info, err := os.Stat(legacyPath); err != nil || !info.IsDir() || legacyPath == newPath {
+		return nil
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return nil
+	}
+	return os.Rename(legacyPath, newPath)
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// Import is a snippet read from another source, to be added to the store.
+type Import struct {
+	Snippet Snippet
+	Content []byte
+}
+
+// Imported is the outcome of importing a snippet.
+type Imported struct {
+	// Snippet is the imported snippet, with a numbered suffix added to its
+	// file name if another snippet was stored at its location.
+	Snippet Snippet
+	// Duplicate is the snippet with the same content if the snippet was
+	// skipped.
+	Duplicate *Snippet
+}
+
+// ReadDir reads every file below dir as a snippet to import, in the folder
+// of its sub-directory, named after the file and in the language of its
+// extension. The folder of files directly in dir is empty.
+func ReadDir(dir string) ([]Import, error) {
+	var imports []Import
+	errs, err := walk(dir, func(folder, name, path string) error {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		snippet := fileSnippet(folder, name, modTime(path))
+		snippet.File = FileName(snippet.Name, snippet.Language)
+		imports = append(imports, Import{Snippet: snippet, Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %w", dir, err)
+	}
+	return imports, joinErrors(errs)
+}
+
+// Import adds the snippets to the top of the metadata, in order, and commits
+// them at once. Snippets whose content is already stored, or imported
+// before, are skipped, and snippets stored at the same location as another
+// one get a numbered suffix, see Available. Nothing is written if dryRun is
+// set, but the outcome is the same.
+func (s *Store) Import(imports []Import, dryRun bool) ([]Imported, error) {
+	for _, imp := range imports {
+		if err := validate(imp.Snippet); err != nil {
+			return nil, err
+		}
+	}
+	if dryRun {
+		snippets, err := s.List()
+		if err != nil {
+			return nil, err
+		}
+		results, _, err := s.importSnippets(snippets, imports, true)
+		return results, err
+	}
+
+	var results []Imported
+	var created []Snippet
+	var importErr error
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		results, created, importErr = s.importSnippets(snippets, imports, false)
+		// The snippets written before one failed are saved anyway, so that
+		// their files are not left without metadata.
+		return append(created, snippets...), nil
+	})
+	if err != nil {
+		return results, err
+	}
+	if len(created) > 0 {
+		if err := s.Commit(importMessage(created)); err != nil {
+			return results, err
+		}
+	}
+	return results, importErr
+}
+
+// importSnippets writes the files of the imported snippets, unless dryRun is
+// set, and returns the outcome with the snippets to add to the metadata. The
+// caller must hold the lock unless dryRun is set.
+func (s *Store) importSnippets(snippets []Snippet, imports []Import, dryRun bool) ([]Imported, []Snippet, error) {
+	hashes := make(map[[sha256.Size]byte]Snippet)
+	for _, snippet := range snippets {
+		content, err := s.Content(snippet)
+		if err != nil {
+			continue
+		}
+		if _, ok := hashes[contentHash(content)]; !ok {
+			hashes[contentHash(content)] = snippet
+		}
+	}
+
+	var results []Imported
+	var created []Snippet
+	now := time.Now()
+	stored := slices.Clone(snippets)
+	for _, imp := range imports {
+		snippet := imp.Snippet
+		// Snippets exported by nap keep their ID, unless it is in use.
+		if snippet.ID == "" || slices.ContainsFunc(stored, func(s Snippet) bool { return s.ID == snippet.ID }) {
+			snippet.ID = NewID()
+		}
+		if snippet.Tags == nil {
+			snippet.Tags = make([]string, 0)
+		}
+		snippet.stamp(now)
+		hash := contentHash(imp.Content)
+		if duplicate, ok := hashes[hash]; ok {
+			results = append(results, Imported{Snippet: snippet, Duplicate: &duplicate})
+			continue
+		}
+		snippet = s.available(stored, snippet)
+		if !dryRun {
+			if err := s.write(snippet, imp.Content); err != nil {
+				return results, created, fmt.Errorf("could not import %s: %w", snippet, err)
+			}
+		}
+		hashes[hash] = snippet
+		stored = append(stored, snippet)
+		created = append(created, snippet)
+		results = append(results, Imported{Snippet: snippet})
+	}
+	return results, created, nil
+}
+
+// importMessage returns the commit message for the imported snippets.
+func importMessage(created []Snippet) string {
+	if len(created) == 1 {
+		return "Import " + created[0].String()
+	}
+	return fmt.Sprintf("Import %d snippets", len(created))
+}
+
+// contentHash returns the hash used to find snippets with the same content,
+// which ignores trailing whitespace.
+func contentHash(content []byte) [sha256.Size]byte {
+	return sha256.Sum256(bytes.TrimRight(content, " \t\r\n"))
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestImport(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	create(t, st, Snippet{Folder: "misc", Name: "a", File: "a.go", Language: "go"})
+
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"b.go":         "package b",
+		"work/a.go":    "package a\n",
+		"work/go/c.go": "package c",
+		".git/config":  "[core]",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Logf("could not write file: %v", err)
+			t.FailNow()
+		}
+	}
+	imports, err := ReadDir(dir)
+	if err != nil {
+		t.Logf("could not read dir: %v", err)
+		t.FailNow()
+	}
+	for i := range imports {
+		if imports[i].Snippet.Folder == "" {
+			imports[i].Snippet.Folder = "misc"
+		}
+	}
+	// The same file imported twice is only imported once.
+	imports = append(imports, Import{Snippet: Snippet{Folder: "misc", Name: "b", File: "b.go", Language: "go"}, Content: []byte("package c\n")})
+
+	paths := func(results []Imported) (imported, skipped []string) {
+		for _, result := range results {
+			if result.Duplicate != nil {
+				skipped = append(skipped, result.Snippet.Path()+"="+result.Duplicate.Path())
+			} else {
+				imported = append(imported, result.Snippet.Path())
+			}
+		}
+		slices.Sort(imported)
+		slices.Sort(skipped)
+		return imported, skipped
+	}
+	wantImported := []string{"misc/b.go", "work/go/c.go"}
+	wantSkipped := []string{"misc/b.go=work/go/c.go", "work/a.go=misc/a.go"}
+
+	for _, dryRun := range []bool{true, false} {
+		results, err := st.Import(imports, dryRun)
+		if err != nil {
+			t.Logf("could not import snippets: %v", err)
+			t.FailNow()
+		}
+		imported, skipped := paths(results)
+		if !slices.Equal(imported, wantImported) || !slices.Equal(skipped, wantSkipped) {
+			t.Logf("import is incorrect: want %v and %v but got %v and %v", wantImported, wantSkipped, imported, skipped)
+			t.FailNow()
+		}
+		snippets, _ := st.List()
+		want := 3
+		if dryRun {
+			want = 1
+		}
+		if len(snippets) != want {
+			t.Logf("snippet count is incorrect: want %d but got %d", want, len(snippets))
+			t.FailNow()
+		}
+	}
+
+	// Importing again skips everything, and a new snippet at a taken
+	// location gets a suffix.
+	imports = append(imports, Import{Snippet: Snippet{Folder: "misc", Name: "b", File: "b.go", Language: "go"}, Content: []byte("package d")})
+	results, err := st.Import(imports, false)
+	if err != nil {
+		t.Logf("could not import snippets: %v", err)
+		t.FailNow()
+	}
+	if imported, _ := paths(results); !slices.Equal(imported, []string{"misc/b-2.go"}) {
+		t.Logf("import is incorrect: want [misc/b-2.go] but got %v", imported)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package store
+
+import "os"
+
+// lockFile is a no-op on platforms without flock(2). Writes are still atomic,
+// but concurrent read-modify-write cycles are not serialized.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without flock(2).
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import "golang.org/x/exp/slices"
+
+// Merge performs a three-way merge of snippet metadata, identifying snippets
+// by their ID, or by their path for snippets without one.
+//
+// base is the common ancestor that both ours and theirs were derived from.
+// Snippets only added in theirs are placed first, as new snippets are created
+// at the top. Snippets removed or modified in theirs but left untouched in
+// ours take the change from theirs. Otherwise ours wins, including its order.
+func Merge(base, ours, theirs []Snippet) []Snippet {
+	var merged []Snippet
+	for _, t := range theirs {
+		if find(base, t) < 0 && find(ours, t) < 0 {
+			merged = append(merged, t)
+		}
+	}
+
+	for _, o := range ours {
+		b, t := find(base, o), find(theirs, o)
+		switch {
+		case b >= 0 && t < 0 && equal(base[b], o):
+			// removed by them
+			continue
+		case b >= 0 && t >= 0 && equal(base[b], o):
+			merged = append(merged, theirs[t])
+		default:
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// equal reports whether two snippets hold the same metadata.
+func equal(a, b Snippet) bool {
+	return a.ID == b.ID &&
+		a.Folder == b.Folder &&
+		a.Name == b.Name &&
+		a.File == b.File &&
+		a.Language == b.Language &&
+		a.Favorite == b.Favorite &&
+		a.Created.Equal(b.Created) &&
+		a.Modified.Equal(b.Modified) &&
+		a.LastUsed.Equal(b.LastUsed) &&
+		a.Uses == b.Uses &&
+		slices.Equal(a.Tags, b.Tags)
+}
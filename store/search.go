@@ -0,0 +1,57 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// Match is a line of a snippet matching a search.
+type Match struct {
+	Snippet Snippet
+	// Line is the line number of the match, starting at 1.
+	Line int
+	// Text is the content of the line, without the line break.
+	Text string
+	// Indices holds the start and end byte offsets in Text of every match on
+	// the line.
+	Indices [][]int
+}
+
+// Search returns every line of the snippets' content matching re. Snippets
+// which cannot be read are skipped and reported in the returned error.
+func (s *Store) Search(snippets []Snippet, re *regexp.Regexp) ([]Match, error) {
+	var matches []Match
+	var errs []string
+	for _, snippet := range snippets {
+		content, err := s.Content(snippet)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("could not read %q: %v", snippet.Path(), err))
+			continue
+		}
+		for _, match := range Grep(content, re) {
+			match.Snippet = snippet
+			matches = append(matches, match)
+		}
+	}
+	return matches, joinErrors(errs)
+}
+
+// Grep returns every line of content matching re. The Snippet of the
+// returned matches is left empty.
+func Grep(content []byte, re *regexp.Regexp) []Match {
+	var matches []Match
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		indices := re.FindAllIndex(line, -1)
+		if len(indices) == 0 {
+			continue
+		}
+		matches = append(matches, Match{
+			Line:    i + 1,
+			Text:    string(line),
+			Indices: indices,
+		})
+	}
+	return matches
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Snippet represents a snippet of code in a language.
+// It is nested within a folder and can be tagged with metadata.
+type Snippet struct {
+	ID     string   `json:"id"`
+	Tags   []string `json:"tags"`
+	Folder string   `json:"folder"`
+	// Created is when the snippet was created and Modified when its content
+	// last changed. LastUsed is when it was last copied, printed or edited,
+	// or zero, and Uses how many times it was.
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+	LastUsed time.Time `json:"last_used"`
+	Uses     int       `json:"uses,omitempty"`
+	Favorite bool      `json:"favorite"`
+	Name     string    `json:"title"`
+	File     string    `json:"file"`
+	Language string    `json:"language"`
+
+	// date is the only date of snippets saved before they had creation and
+	// modification dates, to migrate them.
+	date time.Time
+}
+
+// UnmarshalJSON reads a snippet, keeping the date of legacy snippets.
+func (s *Snippet) UnmarshalJSON(b []byte) error {
+	type snippet Snippet
+	var legacy struct {
+		snippet
+		Date time.Time `json:"date"`
+	}
+	if err := json.Unmarshal(b, &legacy); err != nil {
+		return err
+	}
+	*s = Snippet(legacy.snippet)
+	s.date = legacy.Date
+	return nil
+}
+
+// stamp gives the dates of a new snippet which has none, from its legacy
+// date if it has one.
+func (s *Snippet) stamp(now time.Time) {
+	if s.Created.IsZero() {
+		s.Created = s.date
+	}
+	if s.Created.IsZero() {
+		s.Created = now
+	}
+	if s.Modified.IsZero() {
+		s.Modified = s.Created
+	}
+}
+
+// String returns the folder/name.ext of the snippet.
+func (s Snippet) String() string {
+	return fmt.Sprintf("%s/%s.%s", s.Folder, s.Name, s.Language)
+}
+
+// LegacyPath returns the legacy path <folder>-<file>
+func (s Snippet) LegacyPath() string {
+	return s.File
+}
+
+// Path returns the path <folder>/<file>
+func (s Snippet) Path() string {
+	return filepath.Join(s.Folder, s.File)
+}
+
+// Same reports whether both are the same snippet: by their IDs when both
+// have one, which survive renames, or by their paths otherwise.
+func (s Snippet) Same(other Snippet) bool {
+	if s.ID != "" && other.ID != "" {
+		return s.ID == other.ID
+	}
+	return s.Path() == other.Path()
+}
+
+// NewID returns a new random snippet ID.
+func NewID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// legacyID returns the ID given to a snippet created before snippets had
+// IDs. It is derived from the snippet, so that copies of the same metadata
+// migrated on different machines agree on the ID.
+func legacyID(s Snippet) string {
+	sum := sha1.Sum([]byte(s.Path() + "\x00" + s.date.UTC().Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// FileName returns the file name of a snippet with the given name and
+// language. Characters which are not allowed in file names, such as path
+// separators, are replaced so that the name itself can be free-form.
+func FileName(name, language string) string {
+	file := sanitize(name)
+	if file == "" {
+		file = "snippet"
+	}
+	if language = sanitize(language); language != "" {
+		file += "." + language
+	}
+	return file
+}
+
+// sanitize replaces the characters of s which are not allowed in file names
+// and trims the leading dots, which would hide the file.
+func sanitize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, s)
+	return strings.TrimLeft(strings.TrimSpace(s), ".")
+}
+
+// InFolder reports whether the snippet is in the folder or in one of its
+// nested folders.
+func (s Snippet) InFolder(folder string) bool {
+	folder = strings.Trim(folder, "/")
+	return s.Folder == folder || nested(s.Folder, folder)
+}
+
+// FilterValue is the snippet filter value that can be used when searching.
+func (s Snippet) FilterValue() string {
+	return s.Folder + "/" + s.Name + "\n" + "+" + strings.Join(s.Tags, "+") + "\n" + s.Language
+}
+
+// HasTag reports whether the snippet is tagged with tag.
+func (s Snippet) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
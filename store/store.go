@@ -0,0 +1,729 @@
+// Package store manages snippets on disk.
+//
+// Snippets live as plain files nested in folders under a home directory, and
+// their metadata (name, language, tags, ...) is kept in a single JSON file at
+// the root of that directory.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	// ErrNotFound is returned when a snippet is not part of the store.
+	ErrNotFound = errors.New("snippet not found")
+	// ErrExists is returned when an operation would replace another snippet.
+	ErrExists = errors.New("snippet already exists")
+	// ErrInvalidName is returned for a folder or file name which cannot be
+	// stored, such as one with a path separator or a hidden one.
+	ErrInvalidName = errors.New("invalid name")
+)
+
+// Store provides access to the snippets in a home directory and the metadata
+// file describing them.
+type Store struct {
+	home string
+	file string
+	// gitMode is whether changes are committed to git, see EnableGit, and
+	// gitEnv the environment git runs with.
+	gitMode bool
+	gitEnv  []string
+}
+
+// New returns a store for the snippets in home, with the metadata kept in the
+// given file name relative to home.
+func New(home, file string) *Store {
+	return &Store{home: home, file: file}
+}
+
+// Home returns the directory the snippets are stored in.
+func (s *Store) Home() string {
+	return s.home
+}
+
+// FilePath returns the absolute path of the snippet file.
+func (s *Store) FilePath(snippet Snippet) string {
+	return filepath.Join(s.home, snippet.Path())
+}
+
+// metadataPath returns the absolute path of the metadata file.
+func (s *Store) metadataPath() string {
+	return filepath.Join(s.home, s.file)
+}
+
+// Load returns all the snippets in the store after migrating legacy snippets
+// and reconciling the metadata with the files on disk.
+func (s *Store) Load() ([]Snippet, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	snippets, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	snippets, migrated, migrateErr := s.migrate(snippets)
+	snippets, scanned, scanErr := s.scan(snippets)
+	if migrated || scanned {
+		if err := s.save(snippets); err != nil {
+			return snippets, err
+		}
+	}
+	// Snippets edited or added outside of nap are committed in git mode.
+	if s.gitMode {
+		if err := s.commit("Update snippets"); err != nil {
+			return snippets, err
+		}
+	}
+	if migrateErr != nil {
+		return snippets, migrateErr
+	}
+	return snippets, scanErr
+}
+
+// List returns all the snippets read from the metadata file. The home
+// directory and an empty metadata file are created if they do not exist.
+func (s *Store) List() ([]Snippet, error) {
+	file := s.metadataPath()
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(s.home, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("unable to create directory %s: %w", s.home, err)
+		}
+		if err := writeFileAtomic(file, []byte("[]"), 0o644); err != nil {
+			return nil, fmt.Errorf("unable to create file %s: %w", file, err)
+		}
+		return []Snippet{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snippets []Snippet
+	if err := json.Unmarshal(b, &snippets); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %s: %w", file, err)
+	}
+	return snippets, nil
+}
+
+// Save replaces the metadata file with the given snippets, discarding any
+// changes made by others since they were read. See SaveChanges to keep them.
+func (s *Store) Save(snippets []Snippet) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.save(snippets)
+}
+
+// SaveChanges saves snippets, a modified copy of base, to the metadata file.
+//
+// If the metadata on disk changed since base was read, for example because
+// another nap process added a snippet in the meantime, those changes are
+// merged in rather than overwritten. See Merge for how conflicts are resolved.
+func (s *Store) SaveChanges(base, snippets []Snippet) error {
+	err := s.modify(func(theirs []Snippet) ([]Snippet, error) {
+		return Merge(base, snippets, theirs), nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit("Update snippets")
+}
+
+// save writes the snippets to the metadata file. The caller must hold the
+// lock.
+func (s *Store) save(snippets []Snippet) error {
+	b, err := json.Marshal(snippets)
+	if err != nil {
+		return fmt.Errorf("could not marshal snippets: %w", err)
+	}
+	if err := writeFileAtomic(s.metadataPath(), b, 0o644); err != nil {
+		return fmt.Errorf("could not save snippets file: %w", err)
+	}
+	return nil
+}
+
+// modify runs a read-modify-write cycle on the metadata file while holding
+// the lock. The snippets returned by fn are saved unless it fails.
+func (s *Store) modify(fn func([]Snippet) ([]Snippet, error)) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	snippets, err := s.List()
+	if err != nil {
+		return err
+	}
+	snippets, err = fn(snippets)
+	if err != nil {
+		return err
+	}
+	return s.save(snippets)
+}
+
+// Get returns the snippet stored at the given <folder>/<file> path.
+func (s *Store) Get(path string) (Snippet, error) {
+	snippets, err := s.List()
+	if err != nil {
+		return Snippet{}, err
+	}
+	idx := indexOf(snippets, filepath.Clean(path))
+	if idx < 0 {
+		return Snippet{}, ErrNotFound
+	}
+	return snippets[idx], nil
+}
+
+// Content returns the contents of the snippet file.
+func (s *Store) Content(snippet Snippet) ([]byte, error) {
+	return os.ReadFile(s.FilePath(snippet))
+}
+
+// Create writes a new snippet file with the given content and adds the
+// snippet to the top of the metadata, with a new ID unless it has one and
+// created now unless it has dates. An existing snippet at the same path is
+// overwritten.
+func (s *Store) Create(snippet Snippet, content []byte) error {
+	if err := validate(snippet); err != nil {
+		return err
+	}
+	if snippet.ID == "" {
+		snippet.ID = NewID()
+	}
+	snippet.stamp(time.Now())
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if err := s.write(snippet, content); err != nil {
+			return nil, err
+		}
+		if idx := indexOf(snippets, snippet.Path()); idx >= 0 {
+			snippets = append(snippets[:idx], snippets[idx+1:]...)
+		}
+		return append([]Snippet{snippet}, snippets...), nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit("Add " + snippet.String())
+}
+
+// Write replaces the contents of the snippet file, creating the folder if
+// needed, and updates its modification date. Both the previous and the new
+// contents are recorded in the history of the snippet.
+func (s *Store) Write(snippet Snippet, content []byte) error {
+	if err := s.write(snippet, content); err != nil {
+		return err
+	}
+	if _, err := s.touch(snippet, modified(time.Now())); err != nil {
+		return err
+	}
+	return s.Commit("Edit " + snippet.String())
+}
+
+// write replaces the contents of the snippet file without committing it.
+func (s *Store) write(snippet Snippet, content []byte) error {
+	path := s.FilePath(snippet)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create folder: %w", err)
+	}
+	if err := s.Record(snippet); err != nil {
+		return fmt.Errorf("unable to record history: %w", err)
+	}
+	if err := writeFileAtomic(path, content, 0o644); err != nil {
+		return fmt.Errorf("unable to write snippet: %w", err)
+	}
+	return s.record(snippet, content)
+}
+
+// Append appends content to the end of the snippet file and records the
+// result in the history of the snippet.
+func (s *Store) Append(snippet Snippet, content []byte) error {
+	if err := s.Record(snippet); err != nil {
+		return fmt.Errorf("unable to record history: %w", err)
+	}
+	f, err := os.OpenFile(s.FilePath(snippet), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	_, err = s.Edited(snippet)
+	return err
+}
+
+// Edited records the content of the snippet file in its history, updates
+// its modification date, records the use and commits it. It is called after
+// the file was changed by other means than the store, such as an editor, and
+// returns the snippet with its new dates.
+func (s *Store) Edited(snippet Snippet) (Snippet, error) {
+	if err := s.Record(snippet); err != nil {
+		return snippet, err
+	}
+	now := time.Now()
+	snippet, err := s.touch(snippet, modified(now), used(now))
+	if err != nil {
+		return snippet, err
+	}
+	return snippet, s.Commit("Edit " + snippet.String())
+}
+
+// Used records that the snippet was used, such as copied or printed, now and
+// returns the snippet with its new date and number of uses. Uses are not
+// committed on their own in git mode, but along with the next change.
+func (s *Store) Used(snippet Snippet) (Snippet, error) {
+	return s.touch(snippet, used(time.Now()))
+}
+
+// modified returns a function updating the modification date of a snippet.
+func modified(now time.Time) func(*Snippet) {
+	return func(snippet *Snippet) {
+		snippet.Modified = now
+	}
+}
+
+// used returns a function recording a use of a snippet.
+func used(now time.Time) func(*Snippet) {
+	return func(snippet *Snippet) {
+		snippet.LastUsed = now
+		snippet.Uses++
+	}
+}
+
+// touch updates the dates of the snippet with fns in the metadata and returns
+// the updated snippet, or the given one updated if it is not saved.
+func (s *Store) touch(snippet Snippet, fns ...func(*Snippet)) (Snippet, error) {
+	update := func(snippet *Snippet) {
+		for _, fn := range fns {
+			fn(snippet)
+		}
+	}
+	update(&snippet)
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if idx := find(snippets, snippet); idx >= 0 {
+			update(&snippets[idx])
+			snippet = snippets[idx]
+		}
+		return snippets, nil
+	})
+	return snippet, err
+}
+
+// Update replaces the metadata of the snippet, keeping its location. See Move
+// to change it.
+func (s *Store) Update(snippet Snippet) error {
+	var old Snippet
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		idx := find(snippets, snippet)
+		if idx < 0 {
+			return nil, ErrNotFound
+		}
+		old = snippets[idx]
+		snippet.Folder, snippet.File = old.Folder, old.File
+		snippets[idx] = snippet
+		return snippets, nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit(updateMessage(old, snippet))
+}
+
+// updateMessage describes the change of the metadata of a snippet.
+func updateMessage(before, after Snippet) string {
+	switch {
+	case !before.Favorite && after.Favorite:
+		return "Star " + after.String()
+	case before.Favorite && !after.Favorite:
+		return "Unstar " + after.String()
+	case !slices.Equal(before.Tags, after.Tags) && len(after.Tags) == 0:
+		return "Untag " + after.String()
+	case !slices.Equal(before.Tags, after.Tags):
+		return fmt.Sprintf("Tag %s with #%s", after, strings.Join(after.Tags, " #"))
+	}
+	return "Update " + after.String()
+}
+
+// Move moves the file of the snippet from to the location of the snippet to
+// and replaces its metadata, keeping the ID of from. Moving onto another
+// existing snippet fails with ErrExists, see Replace and Available.
+func (s *Store) Move(from, to Snippet) error {
+	if err := validate(to); err != nil {
+		return err
+	}
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		idx := find(snippets, from)
+		if idx < 0 {
+			return nil, ErrNotFound
+		}
+		oldPath, newPath := s.FilePath(snippets[idx]), s.FilePath(to)
+		if oldPath != newPath {
+			if s.taken(snippets, to.Path()) {
+				return nil, ErrExists
+			}
+			if err := os.MkdirAll(filepath.Dir(newPath), os.ModePerm); err != nil {
+				return nil, fmt.Errorf("unable to create folder: %w", err)
+			}
+			if err := os.Rename(oldPath, newPath); err != nil {
+				return nil, err
+			}
+		}
+		to.ID = snippets[idx].ID
+		snippets[idx] = to
+		return snippets, nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit(fmt.Sprintf("Rename %s to %s", from, to))
+}
+
+// Replace moves the snippet from to the location of the snippet to like Move,
+// moving any other snippet found there to the trash first. The replaced
+// snippet is returned, if there was one.
+func (s *Store) Replace(from, to Snippet) (*Trashed, error) {
+	if err := validate(to); err != nil {
+		return nil, err
+	}
+	replaced, err := s.clear(to, func(other Snippet) bool { return !other.Same(from) })
+	if err != nil {
+		return replaced, err
+	}
+	if _, err := os.Stat(s.FilePath(to)); err == nil && s.FilePath(from) != s.FilePath(to) {
+		// The file was added since the metadata was last scanned.
+		if err := os.Remove(s.FilePath(to)); err != nil {
+			return replaced, err
+		}
+	}
+	return replaced, s.Move(from, to)
+}
+
+// Overwrite creates the snippet like Create, moving any snippet stored at its
+// location to the trash first. The replaced snippet is returned, if there was
+// one.
+func (s *Store) Overwrite(snippet Snippet, content []byte) (*Trashed, error) {
+	if err := validate(snippet); err != nil {
+		return nil, err
+	}
+	replaced, err := s.clear(snippet, func(Snippet) bool { return true })
+	if err != nil {
+		return replaced, err
+	}
+	return replaced, s.Create(snippet, content)
+}
+
+// clear moves the snippet stored at the location of the snippet to the trash
+// if replace returns true for it, and returns it.
+func (s *Store) clear(snippet Snippet, replace func(Snippet) bool) (*Trashed, error) {
+	other, err := s.Get(snippet.Path())
+	if err != nil || !replace(other) {
+		return nil, nil
+	}
+	trashed, err := s.Trash(other)
+	if err != nil {
+		return nil, fmt.Errorf("unable to replace %s: %w", other, err)
+	}
+	return &trashed, nil
+}
+
+// Available returns the snippet with a numbered suffix added to its file
+// name, such as name-2.go, if another snippet is stored at its location.
+func (s *Store) Available(snippet Snippet) (Snippet, error) {
+	snippets, err := s.List()
+	if err != nil {
+		return snippet, err
+	}
+	return s.available(snippets, snippet), nil
+}
+
+// available is Available among the given snippets.
+func (s *Store) available(snippets []Snippet, snippet Snippet) Snippet {
+	ext := filepath.Ext(snippet.File)
+	base := strings.TrimSuffix(snippet.File, ext)
+	available := snippet
+	for n := 2; ; n++ {
+		idx := indexOf(snippets, available.Path())
+		if (idx >= 0 && snippets[idx].Same(snippet)) || !s.taken(snippets, available.Path()) {
+			return available
+		}
+		available.File = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// taken returns whether a snippet or a file is at the <folder>/<file> path.
+func (s *Store) taken(snippets []Snippet, path string) bool {
+	if indexOf(snippets, path) >= 0 {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(s.home, path))
+	return err == nil
+}
+
+// validate returns ErrInvalidName if the folder or file of the snippet cannot
+// be stored in home.
+func validate(snippet Snippet) error {
+	if err := validFolder(snippet.Folder); err != nil {
+		return err
+	}
+	if snippet.File == "" || snippet.File != sanitize(snippet.File) {
+		return fmt.Errorf("%w: file %q", ErrInvalidName, snippet.File)
+	}
+	return nil
+}
+
+// Delete removes the snippet file and its metadata. Its history is kept,
+// with the latest content recorded first.
+func (s *Store) Delete(snippet Snippet) error {
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if err := s.Record(snippet); err != nil {
+			return nil, fmt.Errorf("unable to record history: %w", err)
+		}
+		if err := os.Remove(s.FilePath(snippet)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		idx := find(snippets, snippet)
+		if idx < 0 {
+			return nil, ErrNotFound
+		}
+		return append(snippets[:idx], snippets[idx+1:]...), nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Commit("Delete " + snippet.String())
+}
+
+// Migrate migrates any legacy snippet <dir>-<file> format to the new
+// <dir>/<file> format, gives an ID to the snippets without one and replaces
+// the date of legacy snippets with creation and modification dates.
+func (s *Store) Migrate(snippets []Snippet) ([]Snippet, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return snippets, err
+	}
+	defer unlock()
+
+	snippets, migrated, err := s.migrate(snippets)
+	if migrated {
+		if err := s.save(snippets); err != nil {
+			return snippets, err
+		}
+	}
+	return snippets, err
+}
+
+func (s *Store) migrate(snippets []Snippet) ([]Snippet, bool, error) {
+	var migrated bool
+	var errs []string
+	for idx, snippet := range snippets {
+		if snippet.ID == "" {
+			snippet.ID = legacyID(snippet)
+			snippets[idx] = snippet
+			migrated = true
+		}
+		// Snippets only had a creation date, which was not updated on edits.
+		if snippet.Created.IsZero() && !snippet.date.IsZero() {
+			snippet.Created, snippet.Modified = snippet.date, snippet.date
+			if info, err := os.Stat(s.FilePath(snippet)); err == nil && info.ModTime().After(snippet.Modified) {
+				snippet.Modified = info.ModTime()
+			}
+			snippets[idx] = snippet
+			migrated = true
+		}
+		// Revisions were kept by location rather than by ID.
+		if err := s.migrateHistory(snippet); err != nil {
+			errs = append(errs, fmt.Sprintf("could not move the history of %s: %v", snippet, err))
+		}
+		legacyPath := filepath.Join(s.home, snippet.LegacyPath())
+		if _, err := os.Stat(legacyPath); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Sprintf("could not access %q: %v", legacyPath, err))
+			}
+			continue
+		}
+		file := strings.TrimPrefix(snippet.LegacyPath(), fmt.Sprintf("%s-", snippet.Folder))
+		newDir := filepath.Join(s.home, snippet.Folder)
+		newPath := filepath.Join(newDir, file)
+		if err := os.MkdirAll(newDir, os.ModePerm); err != nil {
+			errs = append(errs, fmt.Sprintf("could not create %q: %v", newDir, err))
+			continue
+		}
+		if err := os.Rename(legacyPath, newPath); err != nil {
+			errs = append(errs, fmt.Sprintf("could not move %q to %q: %v", legacyPath, newPath, err))
+		}
+		migrated = true
+		snippet.File = file
+		snippets[idx] = snippet
+	}
+	return snippets, migrated, joinErrors(errs)
+}
+
+// Scan scans for any new/removed snippet files and updates the metadata
+// accordingly.
+func (s *Store) Scan(snippets []Snippet) ([]Snippet, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return snippets, err
+	}
+	defer unlock()
+
+	snippets, modified, err := s.scan(snippets)
+	if modified {
+		if err := s.save(snippets); err != nil {
+			return snippets, err
+		}
+	}
+	return snippets, err
+}
+
+func (s *Store) scan(snippets []Snippet) ([]Snippet, bool, error) {
+	var modified bool
+	snippetExists := func(path string) bool {
+		return indexOf(snippets, path) >= 0
+	}
+
+	// Files at the root of home are not snippets.
+	errs, err := walk(s.home, func(folder, name, path string) error {
+		if folder != "" && !snippetExists(filepath.Join(folder, name)) {
+			snippets = append(snippets, fileSnippet(folder, name, modTime(path)))
+			modified = true
+		}
+		return nil
+	})
+	if err != nil {
+		return snippets, false, fmt.Errorf("could not scan home: %w", err)
+	}
+
+	var idx int
+	for _, snippet := range snippets {
+		if _, err := os.Stat(s.FilePath(snippet)); errors.Is(err, fs.ErrNotExist) {
+			modified = true
+			continue
+		}
+		snippets[idx] = snippet
+		idx++
+	}
+	snippets = snippets[:idx]
+
+	return snippets, modified, joinErrors(errs)
+}
+
+// walk calls fn with the folder, relative to root and slash separated, the
+// name and the path of every file below root. Folders nest to any depth, the
+// folder of files at the root is empty. Hidden directories, such as the
+// history and the trash, and temporary files are skipped. Directories which
+// cannot be read are reported in the returned errors.
+func walk(root string, fn func(folder, name, path string) error) ([]string, error) {
+	var errs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			errs = append(errs, fmt.Sprintf("could not scan %q: %v", path, err))
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isTempFile(entry.Name()) {
+			return nil
+		}
+		folder, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if folder == "." {
+			folder = ""
+		}
+		return fn(filepath.ToSlash(folder), entry.Name(), path)
+	})
+	return errs, err
+}
+
+// fileSnippet returns a new snippet for the file with the given name, named
+// after the file and in the language of its extension, and dated as the file
+// was last modified.
+func fileSnippet(folder, name string, date time.Time) Snippet {
+	ext := filepath.Ext(name)
+	return Snippet{
+		ID:       NewID(),
+		Folder:   folder,
+		Created:  date,
+		Modified: date,
+		Name:     strings.TrimSuffix(name, ext),
+		File:     name,
+		Language: strings.TrimPrefix(ext, "."),
+		Tags:     make([]string, 0),
+	}
+}
+
+// modTime returns when the file was last modified, or now if it is unknown.
+func modTime(path string) time.Time {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime()
+	}
+	return time.Now()
+}
+
+// indexOf returns the index of the snippet with the given <folder>/<file>
+// path or -1 if there is none.
+func indexOf(snippets []Snippet, path string) int {
+	for i, snippet := range snippets {
+		if snippet.Path() == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// validFolder returns ErrInvalidName if the folder cannot be stored in home.
+// Folders may be nested, separated by slashes.
+func validFolder(folder string) error {
+	for _, name := range strings.Split(folder, "/") {
+		if strings.TrimSpace(name) == "" || strings.HasPrefix(name, ".") || strings.Contains(name, `\`) {
+			return fmt.Errorf("%w: folder %q", ErrInvalidName, folder)
+		}
+	}
+	return nil
+}
+
+// find returns the index of the given snippet, see Snippet.Same, or -1 if
+// there is none.
+func find(snippets []Snippet, snippet Snippet) int {
+	for i, s := range snippets {
+		if s.Same(snippet) {
+			return i
+		}
+	}
+	return -1
+}
+
+// joinErrors combines non-fatal error messages into a single error.
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "\n"))
+}
@@ -0,0 +1,714 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestScan(t *testing.T) {
+	tmp := t.TempDir()
+	st := New(tmp, "snippets.json")
+
+	snippets, err := st.List()
+	if err != nil {
+		t.Logf("could not list snippets: %v", err)
+		t.FailNow()
+	}
+	snippets, err = st.Scan(snippets)
+	if err != nil {
+		t.Logf("could not scan snippets: %v", err)
+		t.FailNow()
+	}
+	initNum := len(snippets)
+
+	tmpSnippetFolder := filepath.Join(tmp, "foo")
+	tmpSnippet := filepath.Join(tmpSnippetFolder, "bar.baz")
+	if err := os.MkdirAll(tmpSnippetFolder, os.ModePerm); err != nil {
+		t.Logf("could not create snippet folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(tmpSnippet, []byte("foo bar baz"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+
+	snippets, _ = st.Scan(snippets)
+	if len(snippets) != initNum+1 {
+		t.Logf("incorrect number of snippets after initial scanning: want %d but got %d", initNum+1, len(snippets))
+		t.FailNow()
+	}
+
+	if err := os.Remove(tmpSnippet); err != nil {
+		t.Logf("could not remove snippet: %v", err)
+		t.FailNow()
+	}
+
+	snippets, _ = st.Scan(snippets)
+	if len(snippets) != initNum {
+		t.Logf("incorrect number of snippets after follow-up scanning: want %d but got %d", initNum, len(snippets))
+		t.FailNow()
+	}
+}
+
+func TestScanNested(t *testing.T) {
+	tmp := t.TempDir()
+	st := New(tmp, "snippets.json")
+	for _, path := range []string{"work/go/http/server.go", "work/py/x.py", ".hidden/a/b.go", "root.go"} {
+		path = filepath.Join(tmp, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+
+	snippets, err := st.Load()
+	if err != nil {
+		t.Logf("could not load snippets: %v", err)
+		t.FailNow()
+	}
+	var got []string
+	for _, snippet := range snippets {
+		if snippet.InFolder("work/go") {
+			got = append(got, snippet.Path())
+		}
+	}
+	if len(snippets) != 2 || !slices.Equal(got, []string{"work/go/http/server.go"}) {
+		t.Logf("nested snippets are incorrect: got %v", snippets)
+		t.FailNow()
+	}
+}
+
+func TestOperations(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	snippet := Snippet{Folder: "foo", Name: "bar", File: "bar.go", Language: "go"}
+
+	t.Run("create", func(t *testing.T) {
+		if err := st.Create(snippet, []byte("package bar")); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+		got, err := st.Get("foo/bar.go")
+		if err != nil {
+			t.Logf("could not get snippet: %v", err)
+			t.FailNow()
+		}
+		if got.String() != "foo/bar.go" {
+			t.Logf("snippet is incorrect: want %q but got %q", "foo/bar.go", got)
+			t.FailNow()
+		}
+		content, err := st.Content(got)
+		if err != nil || string(content) != "package bar" {
+			t.Logf("content is incorrect: want %q but got %q (%v)", "package bar", content, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated := snippet
+		updated.Tags = []string{"baz"}
+		if err := st.Update(updated); err != nil {
+			t.Logf("could not update snippet: %v", err)
+			t.FailNow()
+		}
+		got, _ := st.Get(snippet.Path())
+		if len(got.Tags) != 1 || got.Tags[0] != "baz" {
+			t.Logf("tags are incorrect: want [baz] but got %v", got.Tags)
+			t.FailNow()
+		}
+	})
+
+	t.Run("edited", func(t *testing.T) {
+		before, _ := st.Get(snippet.Path())
+		edited, err := st.Edited(before)
+		if err != nil {
+			t.Logf("could not record edit: %v", err)
+			t.FailNow()
+		}
+		got, _ := st.Get(snippet.Path())
+		if !got.Modified.After(before.Modified) || !got.Modified.Equal(edited.Modified) || !got.Created.Equal(before.Created) {
+			t.Logf("date is incorrect: want after %v and %v but got %v", before.Modified, edited.Modified, got.Modified)
+			t.FailNow()
+		}
+		if got.Uses != before.Uses+1 || !got.LastUsed.Equal(got.Modified) {
+			t.Logf("use is not recorded: got %d uses, last %v", got.Uses, got.LastUsed)
+			t.FailNow()
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		moved := snippet
+		moved.Folder = "qux"
+		if err := st.Move(snippet, moved); err != nil {
+			t.Logf("could not move snippet: %v", err)
+			t.FailNow()
+		}
+		if _, err := st.Get(snippet.Path()); !errors.Is(err, ErrNotFound) {
+			t.Logf("old snippet still exists: %v", err)
+			t.FailNow()
+		}
+		if _, err := os.Stat(st.FilePath(moved)); err != nil {
+			t.Logf("moved file does not exist: %v", err)
+			t.FailNow()
+		}
+
+		other := Snippet{Folder: "qux", Name: "other", File: "other.go", Language: "go"}
+		if err := st.Create(other, nil); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+		if err := st.Move(other, moved); !errors.Is(err, ErrExists) {
+			t.Logf("moving onto an existing snippet should fail: got %v", err)
+			t.FailNow()
+		}
+		snippet = moved
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := st.Delete(snippet); err != nil {
+			t.Logf("could not delete snippet: %v", err)
+			t.FailNow()
+		}
+		snippets, _ := st.List()
+		if len(snippets) != 1 {
+			t.Logf("snippet count is incorrect: want 1 but got %d", len(snippets))
+			t.FailNow()
+		}
+		if _, err := os.Stat(st.FilePath(snippet)); !errors.Is(err, os.ErrNotExist) {
+			t.Logf("deleted file still exists: %v", err)
+			t.FailNow()
+		}
+	})
+}
+
+func TestConcurrentCreate(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			file := fmt.Sprintf("%d.go", i)
+			if err := st.Create(Snippet{Folder: "foo", Name: file, File: file, Language: "go"}, nil); err != nil {
+				t.Errorf("could not create snippet: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	snippets, err := st.List()
+	if err != nil {
+		t.Logf("could not list snippets: %v", err)
+		t.FailNow()
+	}
+	if len(snippets) != n {
+		t.Logf("snippet count is incorrect: want %d but got %d", n, len(snippets))
+		t.FailNow()
+	}
+
+	entries, err := os.ReadDir(st.Home())
+	if err != nil {
+		t.Logf("could not read home: %v", err)
+		t.FailNow()
+	}
+	for _, entry := range entries {
+		if isTempFile(entry.Name()) {
+			t.Logf("temporary file was left behind: %s", entry.Name())
+			t.FailNow()
+		}
+	}
+}
+
+func TestSaveChanges(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	a := Snippet{Folder: "foo", Name: "a", File: "a.go", Language: "go"}
+	b := Snippet{Folder: "foo", Name: "b", File: "b.go", Language: "go"}
+	c := Snippet{Folder: "foo", Name: "c", File: "c.go", Language: "go"}
+
+	for _, snippet := range []Snippet{a, b} {
+		if err := st.Create(snippet, nil); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	base, _ := st.List()
+
+	// Another process adds c and favorites b while we rename a.
+	if err := st.Create(c, nil); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	favorite := b
+	favorite.Favorite = true
+	if err := st.Update(favorite); err != nil {
+		t.Logf("could not update snippet: %v", err)
+		t.FailNow()
+	}
+
+	ours := slices.Clone(base)
+	for i := range ours {
+		if ours[i].Path() == a.Path() {
+			ours[i].Name = "renamed"
+		}
+	}
+	if err := st.SaveChanges(base, ours); err != nil {
+		t.Logf("could not save changes: %v", err)
+		t.FailNow()
+	}
+
+	snippets, _ := st.List()
+	var got []string
+	for _, snippet := range snippets {
+		got = append(got, fmt.Sprintf("%s:%t", snippet.Name, snippet.Favorite))
+	}
+	want := []string{"c:false", "b:true", "renamed:false"}
+	if !slices.Equal(got, want) {
+		t.Logf("merged snippets are incorrect: want %v but got %v", want, got)
+		t.FailNow()
+	}
+}
+
+func TestIDs(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	snippet := Snippet{Folder: "foo", Name: "bar", File: "bar.go", Language: "go"}
+	if err := st.Create(snippet, []byte("package bar")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	created, err := st.Get("foo/bar.go")
+	if err != nil || created.ID == "" {
+		t.Logf("created snippet has no ID: %v (%v)", created, err)
+		t.FailNow()
+	}
+
+	moved := created
+	moved.Folder, moved.Name, moved.File = "baz", "qux", "qux.go"
+	moved.ID = ""
+	if err := st.Move(created, moved); err != nil {
+		t.Logf("could not move snippet: %v", err)
+		t.FailNow()
+	}
+	got, err := st.Get("baz/qux.go")
+	if err != nil || got.ID != created.ID {
+		t.Logf("moved snippet changed ID: want %q but got %q (%v)", created.ID, got.ID, err)
+		t.FailNow()
+	}
+
+	// The snippet is still found by its ID through the old location.
+	created.Favorite = true
+	if err := st.Update(created); err != nil {
+		t.Logf("could not update snippet: %v", err)
+		t.FailNow()
+	}
+	if got, _ := st.Get("baz/qux.go"); !got.Favorite || got.Name != "bar" {
+		t.Logf("snippet was not updated by ID: %v", got)
+		t.FailNow()
+	}
+
+	// Snippets without an ID are given one derived from their metadata.
+	legacy := []Snippet{{Folder: "baz", Name: "qux", File: "qux.go", Language: "go"}}
+	migrated, err := st.Migrate(slices.Clone(legacy))
+	if err != nil || migrated[0].ID == "" {
+		t.Logf("snippet was not given an ID: %v (%v)", migrated, err)
+		t.FailNow()
+	}
+	if again, _ := st.Migrate(slices.Clone(legacy)); again[0].ID != migrated[0].ID {
+		t.Logf("migrated IDs differ: %q and %q", migrated[0].ID, again[0].ID)
+		t.FailNow()
+	}
+}
+
+func TestMoveCollision(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	a := Snippet{Folder: "foo", Name: "a", File: "a.go", Language: "go"}
+	b := Snippet{Folder: "foo", Name: "b", File: "b.go", Language: "go"}
+	for _, snippet := range []Snippet{a, b} {
+		if err := st.Create(snippet, []byte(snippet.Name)); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	a, _ = st.Get("foo/a.go")
+
+	renamed := a
+	renamed.Name, renamed.File = "b", "b.go"
+	if err := st.Move(a, renamed); !errors.Is(err, ErrExists) {
+		t.Logf("moving onto another snippet should fail: %v", err)
+		t.FailNow()
+	}
+	invalid := a
+	invalid.Folder = "../bar"
+	if err := st.Move(a, invalid); !errors.Is(err, ErrInvalidName) {
+		t.Logf("moving out of home should fail: %v", err)
+		t.FailNow()
+	}
+
+	available, err := st.Available(renamed)
+	if err != nil || available.File != "b-2.go" {
+		t.Logf("available file is incorrect: want %q but got %q (%v)", "b-2.go", available.File, err)
+		t.FailNow()
+	}
+
+	replaced, err := st.Replace(a, renamed)
+	if err != nil || replaced == nil || replaced.Snippet.Name != "b" {
+		t.Logf("could not replace snippet: %v (%v)", replaced, err)
+		t.FailNow()
+	}
+	got, err := st.Get("foo/b.go")
+	if err != nil || got.ID != a.ID {
+		t.Logf("replaced snippet is incorrect: want %q but got %q (%v)", a.ID, got.ID, err)
+		t.FailNow()
+	}
+	if content, _ := st.Content(got); string(content) != "a" {
+		t.Logf("replaced content is incorrect: want %q but got %q", "a", content)
+		t.FailNow()
+	}
+	if trash, _ := st.ListTrash(); len(trash) != 1 {
+		t.Logf("replaced snippet should be in the trash: got %v", trash)
+		t.FailNow()
+	}
+
+	overwritten, err := st.Overwrite(Snippet{Folder: "foo", Name: "b", File: "b.go", Language: "go"}, []byte("c"))
+	if err != nil || overwritten == nil || overwritten.Snippet.ID != a.ID {
+		t.Logf("could not overwrite snippet: %v (%v)", overwritten, err)
+		t.FailNow()
+	}
+	if got, _ := st.Get("foo/b.go"); got.ID == a.ID {
+		t.Logf("overwritten snippet should be new: got %q", got.ID)
+		t.FailNow()
+	}
+	if trash, _ := st.ListTrash(); len(trash) != 2 {
+		t.Logf("overwritten snippet should be in the trash: got %v", trash)
+		t.FailNow()
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct{ name, language, want string }{
+		{"Hello", "go", "Hello.go"},
+		{"a/b: c?", "sh", "a-b- c-.sh"},
+		{"..hidden", "txt", "hidden.txt"},
+		{"", "go", "snippet.go"},
+		{"Makefile", "", "Makefile"},
+	}
+	for _, tt := range tests {
+		if got := FileName(tt.name, tt.language); got != tt.want {
+			t.Logf("file name of %q is incorrect: want %q but got %q", tt.name, tt.want, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	a := Snippet{Folder: "foo", Name: "a", File: "a.go", Language: "go"}
+	b := Snippet{Folder: "bar", Name: "b", File: "b.go", Language: "go"}
+	if err := st.Create(a, []byte("package a\n\nfunc Foo() {}\nfunc fooBar() {}\n")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	if err := st.Create(b, []byte("package b\n")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+
+	matches, err := st.Search([]Snippet{a, b}, regexp.MustCompile(`(?i)foo`))
+	if err != nil {
+		t.Logf("could not search snippets: %v", err)
+		t.FailNow()
+	}
+
+	var got []string
+	for _, match := range matches {
+		got = append(got, fmt.Sprintf("%s:%d:%s:%v", match.Snippet.Path(), match.Line, match.Text, match.Indices))
+	}
+	want := []string{
+		"foo/a.go:3:func Foo() {}:[[5 8]]",
+		"foo/a.go:4:func fooBar() {}:[[5 8]]",
+	}
+	if !slices.Equal(got, want) {
+		t.Logf("matches are incorrect: want %q but got %q", want, got)
+		t.FailNow()
+	}
+}
+
+func TestHistory(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	snippet := Snippet{Folder: "foo", Name: "bar", File: "bar.go", Language: "go"}
+
+	if err := st.Create(snippet, []byte("v1")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	// An editor changes the file behind the back of the store.
+	if err := os.WriteFile(st.FilePath(snippet), []byte("v2"), 0o644); err != nil {
+		t.Logf("could not edit snippet: %v", err)
+		t.FailNow()
+	}
+	if err := st.Append(snippet, []byte("+v3")); err != nil {
+		t.Logf("could not append to snippet: %v", err)
+		t.FailNow()
+	}
+	if err := st.Write(snippet, []byte("v2+v3")); err != nil {
+		t.Logf("could not write snippet: %v", err)
+		t.FailNow()
+	}
+
+	revisions, err := st.History(snippet)
+	if err != nil {
+		t.Logf("could not read history: %v", err)
+		t.FailNow()
+	}
+	var got []string
+	for _, r := range revisions {
+		content, _ := st.Revision(snippet, r.Rev)
+		got = append(got, fmt.Sprintf("%d:%s", r.Rev, content))
+	}
+	want := []string{"1:v1", "2:v2", "3:v2+v3"}
+	if !slices.Equal(got, want) {
+		t.Logf("revisions are incorrect: want %v but got %v", want, got)
+		t.FailNow()
+	}
+
+	if err := st.Restore(snippet, 1); err != nil {
+		t.Logf("could not restore snippet: %v", err)
+		t.FailNow()
+	}
+	if content, _ := st.Content(snippet); string(content) != "v1" {
+		t.Logf("restored content is incorrect: want %q but got %q", "v1", content)
+		t.FailNow()
+	}
+	if _, err := st.Revision(snippet, 42); !errors.Is(err, ErrNoRevision) {
+		t.Logf("missing revision should fail: got %v", err)
+		t.FailNow()
+	}
+
+	moved := snippet
+	moved.Folder = "qux"
+	if err := st.Move(snippet, moved); err != nil {
+		t.Logf("could not move snippet: %v", err)
+		t.FailNow()
+	}
+	if revisions, _ := st.History(moved); len(revisions) != 4 {
+		t.Logf("history did not follow the snippet: want 4 revisions but got %d", len(revisions))
+		t.FailNow()
+	}
+	if err := st.Create(snippet, []byte("new")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	if revisions, _ := st.History(snippet); len(revisions) != 1 {
+		t.Logf("new snippet should not inherit the history: want 1 revision but got %d", len(revisions))
+		t.FailNow()
+	}
+}
+
+func TestLegacyHistory(t *testing.T) {
+	tmp := t.TempDir()
+	st := New(tmp, "snippets.json")
+	snippet := Snippet{ID: NewID(), Folder: "foo", Name: "bar", File: "bar.go", Language: "go"}
+	create(t, st, snippet)
+	if err := os.RemoveAll(filepath.Join(tmp, historyDir)); err != nil {
+		t.Logf("could not remove history: %v", err)
+		t.FailNow()
+	}
+	// Revisions used to be kept by location.
+	legacy := filepath.Join(tmp, historyDir, "foo", "bar.go")
+	if err := os.MkdirAll(legacy, os.ModePerm); err != nil {
+		t.Logf("could not create history: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "1"), []byte("v1"), 0o644); err != nil {
+		t.Logf("could not write revision: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := st.Load(); err != nil {
+		t.Logf("could not load snippets: %v", err)
+		t.FailNow()
+	}
+	if content, err := st.Revision(snippet, 1); err != nil || string(content) != "v1" {
+		t.Logf("legacy revision is incorrect: want %q but got %q (%v)", "v1", content, err)
+		t.FailNow()
+	}
+}
+
+func TestTrash(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	date := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	snippet := Snippet{ID: NewID(), Folder: "foo", Name: "bar", File: "bar.go", Language: "go", Tags: []string{"baz"}, Favorite: true, Created: date, Modified: date}
+	if err := st.Create(snippet, []byte("package bar")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+
+	trashed, err := st.Trash(snippet)
+	if err != nil {
+		t.Logf("could not trash snippet: %v", err)
+		t.FailNow()
+	}
+	if _, err := st.Get(snippet.Path()); !errors.Is(err, ErrNotFound) {
+		t.Logf("trashed snippet still exists: %v", err)
+		t.FailNow()
+	}
+	if content, err := st.Content(trashed.Location()); err != nil || string(content) != "package bar" {
+		t.Logf("trashed content is incorrect: want %q but got %q (%v)", "package bar", content, err)
+		t.FailNow()
+	}
+
+	trash, err := st.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].ID != trashed.ID {
+		t.Logf("trash is incorrect: want [%s] but got %v (%v)", trashed.ID, trash, err)
+		t.FailNow()
+	}
+
+	restored, err := st.Untrash(trash[0])
+	if err != nil {
+		t.Logf("could not restore snippet: %v", err)
+		t.FailNow()
+	}
+	got, err := st.Get(snippet.Path())
+	if err != nil || !equal(got, snippet) || !equal(restored, snippet) {
+		t.Logf("restored snippet is incorrect: want %v but got %v (%v)", snippet, got, err)
+		t.FailNow()
+	}
+	if trash, _ := st.ListTrash(); len(trash) != 0 {
+		t.Logf("trash should be empty: got %v", trash)
+		t.FailNow()
+	}
+
+	if _, err := st.Trash(snippet); err != nil {
+		t.Logf("could not trash snippet: %v", err)
+		t.FailNow()
+	}
+	if n, err := st.EmptyTrash(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Logf("recently deleted snippets should be kept: purged %d (%v)", n, err)
+		t.FailNow()
+	}
+	if n, err := st.EmptyTrash(time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Logf("trash should be emptied: purged %d (%v)", n, err)
+		t.FailNow()
+	}
+}
+
+func TestTrashMetadataName(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	snippet := Snippet{ID: NewID(), Folder: "foo", Name: "snippet", File: "snippet.json", Language: "json", Tags: []string{}}
+	if err := st.Create(snippet, []byte(`{"a": 1}`)); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	if _, err := st.Trash(snippet); err != nil {
+		t.Logf("could not trash snippet: %v", err)
+		t.FailNow()
+	}
+
+	trash, err := st.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].Snippet.ID != snippet.ID {
+		t.Logf("trash is incorrect: want [%s] but got %v (%v)", snippet.ID, trash, err)
+		t.FailNow()
+	}
+	if _, err := st.Untrash(trash[0]); err != nil {
+		t.Logf("could not restore snippet: %v", err)
+		t.FailNow()
+	}
+	if content, err := st.Content(snippet); err != nil || string(content) != `{"a": 1}` {
+		t.Logf("restored content is incorrect: want %q but got %q (%v)", `{"a": 1}`, content, err)
+		t.FailNow()
+	}
+}
+
+func TestDates(t *testing.T) {
+	tmp := t.TempDir()
+	st := New(tmp, "snippets.json")
+	date := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	edited := date.Add(24 * time.Hour)
+	metadata := `[
+		{"folder":"foo","title":"old","file":"old.go","language":"go","date":"2022-01-02T03:04:05Z"},
+		{"folder":"foo","title":"edited","file":"edited.go","language":"go","date":"2022-01-02T03:04:05Z"}
+	]`
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "snippets.json"), []byte(metadata), 0o644); err != nil {
+		t.Logf("could not write metadata: %v", err)
+		t.FailNow()
+	}
+	for name, modTime := range map[string]time.Time{"old.go": date.Add(-time.Hour), "edited.go": edited} {
+		path := filepath.Join(tmp, "foo", name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Logf("could not change times: %v", err)
+			t.FailNow()
+		}
+	}
+
+	t.Run("migrate", func(t *testing.T) {
+		snippets, err := st.Load()
+		if err != nil || len(snippets) != 2 {
+			t.Logf("could not load snippets: %v (%v)", snippets, err)
+			t.FailNow()
+		}
+		for _, snippet := range snippets {
+			want := date
+			if snippet.Name == "edited" {
+				want = edited
+			}
+			if !snippet.Created.Equal(date) || !snippet.Modified.Equal(want) || !snippet.LastUsed.IsZero() {
+				t.Logf("dates of %s are incorrect: want %v and %v but got %v and %v", snippet, date, want, snippet.Created, snippet.Modified)
+				t.FailNow()
+			}
+		}
+	})
+
+	t.Run("used", func(t *testing.T) {
+		snippet, _ := st.Get("foo/old.go")
+		var used Snippet
+		for i := 0; i < 2; i++ {
+			var err error
+			if used, err = st.Used(snippet); err != nil {
+				t.Logf("could not record use: %v", err)
+				t.FailNow()
+			}
+		}
+		got, _ := st.Get("foo/old.go")
+		if got.LastUsed.IsZero() || !got.LastUsed.Equal(used.LastUsed) || !got.Modified.Equal(snippet.Modified) {
+			t.Logf("dates are incorrect: got %v", got)
+			t.FailNow()
+		}
+		if got.Uses != 2 || used.Uses != 2 {
+			t.Logf("uses are incorrect: want 2 but got %d and %d", got.Uses, used.Uses)
+			t.FailNow()
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		snippet, _ := st.Get("foo/old.go")
+		if err := st.Write(snippet, []byte("package foo")); err != nil {
+			t.Logf("could not write snippet: %v", err)
+			t.FailNow()
+		}
+		got, _ := st.Get("foo/old.go")
+		if !got.Modified.After(snippet.Modified) || !got.Created.Equal(date) {
+			t.Logf("dates are incorrect: got %v", got)
+			t.FailNow()
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// trashDir is the hidden directory of home keeping the deleted snippets.
+// Each deleted snippet is kept in its own directory, holding the file and
+// the metadata of the snippet.
+const trashDir = ".trash"
+
+// trashMetadata is the name of the metadata file of a deleted snippet. It is
+// hidden so that it cannot be the file of the snippet, whose names never
+// start with a dot. Snippets deleted before were kept with legacyMetadata.
+const (
+	trashMetadata  = ".snippet.json"
+	legacyMetadata = "snippet.json"
+)
+
+// Trashed is a deleted snippet kept in the trash until it is restored or
+// purged.
+type Trashed struct {
+	ID      string    `json:"-"`
+	Deleted time.Time `json:"deleted"`
+	Snippet Snippet   `json:"snippet"`
+}
+
+// Location returns the snippet with its folder pointing to where its file is
+// kept in the trash, so that its content can be read with Content.
+func (t Trashed) Location() Snippet {
+	snippet := t.Snippet
+	snippet.Folder = filepath.Join(trashDir, t.ID)
+	return snippet
+}
+
+// trashPath returns the directory keeping the deleted snippet.
+func (s *Store) trashPath(id string) string {
+	return filepath.Join(s.home, trashDir, id)
+}
+
+// Trash moves the snippet file and its metadata to the trash.
+func (s *Store) Trash(snippet Snippet) (Trashed, error) {
+	if err := s.Record(snippet); err != nil {
+		return Trashed{}, fmt.Errorf("unable to record history: %w", err)
+	}
+	trashed := Trashed{Deleted: time.Now(), Snippet: snippet}
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		idx := find(snippets, snippet)
+		if idx < 0 {
+			return nil, ErrNotFound
+		}
+		var err error
+		trashed, err = s.moveToTrash(snippets[idx], trashed.Deleted)
+		if err != nil {
+			return nil, err
+		}
+		return append(snippets[:idx], snippets[idx+1:]...), nil
+	})
+	if err != nil {
+		return trashed, err
+	}
+	return trashed, s.Commit("Delete " + snippet.String())
+}
+
+// moveToTrash moves the file of the stored snippet to a new directory of the
+// trash along with its metadata, without updating the metadata file. The
+// caller must hold the lock.
+func (s *Store) moveToTrash(snippet Snippet, deleted time.Time) (Trashed, error) {
+	trashed := Trashed{Deleted: deleted, Snippet: snippet}
+	if err := os.MkdirAll(filepath.Join(s.home, trashDir), os.ModePerm); err != nil {
+		return trashed, fmt.Errorf("unable to create trash: %w", err)
+	}
+	dir, err := os.MkdirTemp(filepath.Join(s.home, trashDir), deleted.Format("20060102150405-*"))
+	if err != nil {
+		return trashed, fmt.Errorf("unable to create trash: %w", err)
+	}
+	trashed.ID = filepath.Base(dir)
+
+	b, err := json.Marshal(trashed)
+	if err != nil {
+		os.RemoveAll(dir)
+		return trashed, err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, trashMetadata), b, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return trashed, err
+	}
+	err = os.Rename(s.FilePath(snippet), s.FilePath(trashed.Location()))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		os.RemoveAll(dir)
+		return trashed, err
+	}
+	return trashed, nil
+}
+
+// takeOutOfTrash moves the files of the snippets moved to the trash by
+// moveToTrash back, undoing a change which failed. The caller must hold the
+// lock.
+func (s *Store) takeOutOfTrash(trash []Trashed) {
+	for _, trashed := range trash {
+		_ = os.Rename(s.FilePath(trashed.Location()), s.FilePath(trashed.Snippet))
+		_ = os.RemoveAll(s.trashPath(trashed.ID))
+	}
+}
+
+// ListTrash returns the snippets in the trash, most recently deleted first.
+func (s *Store) ListTrash() ([]Trashed, error) {
+	entries, err := os.ReadDir(filepath.Join(s.home, trashDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var trash []Trashed
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.trashPath(entry.Name()), trashMetadata))
+		if errors.Is(err, fs.ErrNotExist) {
+			b, err = os.ReadFile(filepath.Join(s.trashPath(entry.Name()), legacyMetadata))
+		}
+		if err != nil {
+			continue
+		}
+		var trashed Trashed
+		if err := json.Unmarshal(b, &trashed); err != nil {
+			continue
+		}
+		trashed.ID = entry.Name()
+		trash = append(trash, trashed)
+	}
+	sort.SliceStable(trash, func(i, j int) bool {
+		return trash[i].Deleted.After(trash[j].Deleted)
+	})
+	return trash, nil
+}
+
+// Untrash moves the deleted snippet back to its folder and adds it to the top
+// of the metadata. Restoring onto another existing snippet fails with
+// ErrExists.
+func (s *Store) Untrash(trashed Trashed) (Snippet, error) {
+	snippet := trashed.Snippet
+	err := s.modify(func(snippets []Snippet) ([]Snippet, error) {
+		if _, err := os.Stat(s.FilePath(snippet)); err == nil || indexOf(snippets, snippet.Path()) >= 0 {
+			return nil, ErrExists
+		}
+		if err := os.MkdirAll(filepath.Dir(s.FilePath(snippet)), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("unable to create folder: %w", err)
+		}
+		err := os.Rename(s.FilePath(trashed.Location()), s.FilePath(snippet))
+		if errors.Is(err, fs.ErrNotExist) {
+			err = writeFileAtomic(s.FilePath(snippet), nil, 0o644)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := os.RemoveAll(s.trashPath(trashed.ID)); err != nil {
+			return nil, err
+		}
+		return append([]Snippet{snippet}, snippets...), nil
+	})
+	if err != nil {
+		return snippet, err
+	}
+	return snippet, s.Commit("Restore " + snippet.String())
+}
+
+// Purge permanently removes the deleted snippet from the trash.
+func (s *Store) Purge(trashed Trashed) error {
+	if trashed.ID == "" {
+		return ErrNotFound
+	}
+	return os.RemoveAll(s.trashPath(trashed.ID))
+}
+
+// EmptyTrash permanently removes the snippets deleted before the given time
+// from the trash and returns how many were removed.
+func (s *Store) EmptyTrash(before time.Time) (int, error) {
+	trash, err := s.ListTrash()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, trashed := range trash {
+		if !trashed.Deleted.Before(before) {
+			continue
+		}
+		if err := s.Purge(trashed); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errWatchUnsupported is returned where files cannot be watched natively, in
+// which case they are polled.
+var errWatchUnsupported = errors.New("watching files is not supported")
+
+// Watcher reports changes to the snippets and metadata in home, made by nap
+// or by other programs such as editors, scripts or git.
+type Watcher struct {
+	changes chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	// stop releases the resources of the native watcher, if any.
+	stop func() error
+}
+
+// Watch starts watching home for changes, natively where supported, such as
+// with inotify on Linux, or by scanning home every interval otherwise.
+func (s *Store) Watch(interval time.Duration) (*Watcher, error) {
+	if err := os.MkdirAll(s.home, os.ModePerm); err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		changes: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := s.watch(w); err == nil {
+		return w, nil
+	}
+	go s.poll(w, interval)
+	return w, nil
+}
+
+// Changes returns the channel receiving a value after files changed. Changes
+// made until the value is received are coalesced into it.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Close stops watching home.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		if w.stop != nil {
+			err = w.stop()
+		}
+	})
+	return err
+}
+
+// notify reports a change unless one is already pending.
+func (w *Watcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// fileState is what polling compares to detect that a file changed.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// poll scans home every interval until the watcher is closed, and reports
+// files which were added, removed or modified in the meantime.
+func (s *Store) poll(w *Watcher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := s.snapshot()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		current := s.snapshot()
+		if !sameSnapshot(last, current) {
+			w.notify()
+		}
+		last = current
+	}
+}
+
+// snapshot returns the state of every file in home which is not hidden,
+// including the metadata file, and of every folder.
+func (s *Store) snapshot() map[string]fileState {
+	files := make(map[string]fileState)
+	folders, _ := s.Folders()
+	for _, folder := range folders {
+		files[s.folderPath(folder)] = fileState{}
+	}
+	_, _ = walk(s.home, func(folder, name, path string) error {
+		if strings.HasPrefix(name, ".") {
+			return nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			files[path] = fileState{info.Size(), info.ModTime()}
+		}
+		return nil
+	})
+	return files
+}
+
+// sameSnapshot returns whether no file changed between both snapshots.
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		if other, ok := b[path]; !ok || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+	return true
+}
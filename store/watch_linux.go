@@ -0,0 +1,89 @@
+//go:build linux
+
+package store
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// watchMask selects the inotify events of files being written, created,
+// removed or renamed.
+const watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// watch watches home and every folder in it with inotify. Hidden directories,
+// such as the history and the trash, are not watched.
+func (s *Store) watch(w *Watcher) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	// A non-blocking file is read through the runtime poller, so that closing
+	// it interrupts a pending read.
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	dirs := make(map[int32]string)
+	add := func(dir string) error {
+		return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return err
+			}
+			if path != s.home && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			wd, err := syscall.InotifyAddWatch(fd, path, watchMask)
+			if err != nil {
+				return err
+			}
+			dirs[int32(wd)] = path
+			return nil
+		})
+	}
+	if err := add(s.home); err != nil {
+		f.Close()
+		return err
+	}
+
+	w.stop = f.Close
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			var changed bool
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+				name := strings.TrimRight(string(nameBytes), "\x00")
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+				switch {
+				case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+					changed = true
+				case event.Mask&syscall.IN_IGNORED != 0:
+					delete(dirs, event.Wd)
+				case strings.HasPrefix(name, "."):
+					// hidden directories and temporary files
+				case event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+					if dir, ok := dirs[event.Wd]; ok {
+						_ = add(filepath.Join(dir, name))
+					}
+					changed = true
+				default:
+					changed = true
+				}
+			}
+			if changed {
+				w.notify()
+			}
+		}
+	}()
+	return nil
+}
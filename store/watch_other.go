@@ -0,0 +1,8 @@
+//go:build !linux
+
+package store
+
+// watch is not supported natively, so home is polled.
+func (s *Store) watch(w *Watcher) error {
+	return errWatchUnsupported
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	st := New(t.TempDir(), "snippets.json")
+	create(t, st, Snippet{Folder: "misc", Name: "a", File: "a.go", Language: "go"})
+
+	native, err := st.Watch(time.Hour)
+	if err != nil {
+		t.Logf("could not watch home: %v", err)
+		t.FailNow()
+	}
+	defer native.Close()
+	polling := &Watcher{changes: make(chan struct{}, 1), done: make(chan struct{})}
+	go st.poll(polling, 10*time.Millisecond)
+	defer polling.Close()
+	// Let the polling watcher take its first snapshot.
+	time.Sleep(50 * time.Millisecond)
+
+	expect := func(change func() error) {
+		t.Helper()
+		if err := change(); err != nil {
+			t.Logf("could not change files: %v", err)
+			t.FailNow()
+		}
+		for name, w := range map[string]*Watcher{"native": native, "polling": polling} {
+			select {
+			case <-w.Changes():
+			case <-time.After(2 * time.Second):
+				t.Logf("%s watcher did not report the change", name)
+				t.FailNow()
+			}
+		}
+	}
+	expect(func() error {
+		return os.WriteFile(filepath.Join(st.Home(), "misc", "a.go"), []byte("package changed"), 0o644)
+	})
+	expect(func() error {
+		return os.MkdirAll(filepath.Join(st.Home(), "work", "go"), 0o755)
+	})
+	expect(func() error {
+		return os.WriteFile(filepath.Join(st.Home(), "work", "go", "b.go"), []byte("package b"), 0o644)
+	})
+	expect(func() error {
+		return os.Remove(filepath.Join(st.Home(), "misc", "a.go"))
+	})
+
+	// Changes to the history are not reported.
+	if err := st.Record(Snippet{Folder: "work/go", Name: "b", File: "b.go", Language: "go"}); err != nil {
+		t.Logf("could not record history: %v", err)
+		t.FailNow()
+	}
+	select {
+	case <-native.Changes():
+		t.Log("native watcher reported a change to the history")
+		t.FailNow()
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -39,16 +39,18 @@ type SnippetsBaseStyle struct {
 	DeletedTitleBar    lipgloss.Style
 	DeletedTitle       lipgloss.Style
 	DeletedSubtitle    lipgloss.Style
+	GroupHeader        lipgloss.Style
 }
 
 // FoldersBaseStyle holds the neccessary styling for the folders pane of
 // the application.
 type FoldersBaseStyle struct {
-	Base       lipgloss.Style
-	Title      lipgloss.Style
-	TitleBar   lipgloss.Style
-	Selected   lipgloss.Style
-	Unselected lipgloss.Style
+	Base            lipgloss.Style
+	Title           lipgloss.Style
+	TitleBar        lipgloss.Style
+	DeletedTitleBar lipgloss.Style
+	Selected        lipgloss.Style
+	Unselected      lipgloss.Style
 }
 
 // ContentBaseStyle holds the neccessary styling for the content pane of the
@@ -60,6 +62,20 @@ type ContentBaseStyle struct {
 	LineNumber   lipgloss.Style
 	EmptyHint    lipgloss.Style
 	EmptyHintKey lipgloss.Style
+	Tags         lipgloss.Style
+	Match        lipgloss.Style
+	DiffInsert   lipgloss.Style
+	DiffDelete   lipgloss.Style
+	DiffHunk     lipgloss.Style
+}
+
+// FinderBaseStyle holds the neccessary styling for the finder popup of the
+// application.
+type FinderBaseStyle struct {
+	Base       lipgloss.Style
+	Selected   lipgloss.Style
+	Unselected lipgloss.Style
+	Match      lipgloss.Style
 }
 
 // Styles is the struct of all styles for the application.
@@ -67,6 +83,7 @@ type Styles struct {
 	Snippets SnippetsStyle
 	Folders  FoldersStyle
 	Content  ContentStyle
+	Finder   FinderBaseStyle
 }
 
 var marginStyle = lipgloss.NewStyle().Margin(1, 0, 0, 1)
@@ -100,6 +117,7 @@ func DefaultStyles(config Config) Styles {
 				DeletedTitleBar:    lipgloss.NewStyle().Background(red).Width(35-2).Margin(0, 1, 1, 1).Padding(0, 1).Foreground(white),
 				DeletedTitle:       lipgloss.NewStyle().Foreground(brightRed),
 				DeletedSubtitle:    lipgloss.NewStyle().Foreground(red),
+				GroupHeader:        lipgloss.NewStyle().Foreground(white).Bold(true),
 			},
 			Blurred: SnippetsBaseStyle{
 				Base:               lipgloss.NewStyle().Width(35),
@@ -114,22 +132,25 @@ func DefaultStyles(config Config) Styles {
 				DeletedTitleBar:    lipgloss.NewStyle().Background(red).Width(35-2).Margin(0, 1, 1, 1).Padding(0, 1),
 				DeletedTitle:       lipgloss.NewStyle().Foreground(brightRed),
 				DeletedSubtitle:    lipgloss.NewStyle().Foreground(red),
+				GroupHeader:        lipgloss.NewStyle().Foreground(gray).Bold(true),
 			},
 		},
 		Folders: FoldersStyle{
 			Focused: FoldersBaseStyle{
-				Base:       lipgloss.NewStyle().Width(22),
-				Title:      lipgloss.NewStyle().Padding(0, 1).Foreground(white),
-				TitleBar:   lipgloss.NewStyle().Background(blue).Width(22-2).Margin(0, 1, 1, 1),
-				Selected:   lipgloss.NewStyle().Foreground(brightBlue),
-				Unselected: lipgloss.NewStyle().Foreground(gray),
+				Base:            lipgloss.NewStyle().Width(22),
+				Title:           lipgloss.NewStyle().Padding(0, 1).Foreground(white),
+				TitleBar:        lipgloss.NewStyle().Background(blue).Width(22-2).Margin(0, 1, 1, 1),
+				DeletedTitleBar: lipgloss.NewStyle().Background(red).Width(22-2).Margin(0, 1, 1, 1),
+				Selected:        lipgloss.NewStyle().Foreground(brightBlue),
+				Unselected:      lipgloss.NewStyle().Foreground(gray),
 			},
 			Blurred: FoldersBaseStyle{
-				Base:       lipgloss.NewStyle().Width(22),
-				Title:      lipgloss.NewStyle().Padding(0, 1).Foreground(gray),
-				TitleBar:   lipgloss.NewStyle().Background(black).Width(22-2).Margin(0, 1, 1, 1),
-				Selected:   lipgloss.NewStyle().Foreground(brightBlue),
-				Unselected: lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
+				Base:            lipgloss.NewStyle().Width(22),
+				Title:           lipgloss.NewStyle().Padding(0, 1).Foreground(gray),
+				TitleBar:        lipgloss.NewStyle().Background(black).Width(22-2).Margin(0, 1, 1, 1),
+				DeletedTitleBar: lipgloss.NewStyle().Background(red).Width(22-2).Margin(0, 1, 1, 1),
+				Selected:        lipgloss.NewStyle().Foreground(brightBlue),
+				Unselected:      lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
 			},
 		},
 		Content: ContentStyle{
@@ -140,6 +161,11 @@ func DefaultStyles(config Config) Styles {
 				LineNumber:   lipgloss.NewStyle().Foreground(brightBlack),
 				EmptyHint:    lipgloss.NewStyle().Foreground(gray),
 				EmptyHintKey: lipgloss.NewStyle().Foreground(brightBlue),
+				Tags:         lipgloss.NewStyle().Foreground(brightBlue).Margin(0, 0, 1, 1),
+				Match:        lipgloss.NewStyle().Background(brightBlue).Foreground(black),
+				DiffInsert:   lipgloss.NewStyle().Foreground(brightGreen),
+				DiffDelete:   lipgloss.NewStyle().Foreground(brightRed),
+				DiffHunk:     lipgloss.NewStyle().Foreground(brightBlue),
 			},
 			Blurred: ContentBaseStyle{
 				Base:         lipgloss.NewStyle().Margin(0, 1),
@@ -148,7 +174,18 @@ func DefaultStyles(config Config) Styles {
 				LineNumber:   lipgloss.NewStyle().Foreground(black),
 				EmptyHint:    lipgloss.NewStyle().Foreground(gray),
 				EmptyHintKey: lipgloss.NewStyle().Foreground(brightBlue),
+				Tags:         lipgloss.NewStyle().Foreground(gray).Margin(0, 0, 1, 1),
+				Match:        lipgloss.NewStyle().Background(blue).Foreground(white),
+				DiffInsert:   lipgloss.NewStyle().Foreground(green),
+				DiffDelete:   lipgloss.NewStyle().Foreground(red),
+				DiffHunk:     lipgloss.NewStyle().Foreground(blue),
 			},
 		},
+		Finder: FinderBaseStyle{
+			Base:       lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(blue).Padding(0, 1),
+			Selected:   lipgloss.NewStyle().Foreground(brightBlue),
+			Unselected: lipgloss.NewStyle().Foreground(gray),
+			Match:      lipgloss.NewStyle().Foreground(brightGreen).Bold(true),
+		},
 	}
 }
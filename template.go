@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maaslalani/nap/store"
+)
+
+// templateTag is the tag marking snippets whose placeholders are filled in
+// when they are printed or copied. Templates are opt-in since ${...} is valid
+// syntax in many languages, such as shell scripts or JavaScript.
+const templateTag = "template"
+
+// placeholderPattern matches ${name} and ${name:default} placeholders, as well
+// as $${ which escapes a literal ${.
+var placeholderPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_.-]*)(?::([^}]*))?\}`)
+
+// placeholder is a variable in a template snippet, with an optional default
+// value.
+type placeholder struct {
+	Name    string
+	Default string
+}
+
+// builtins are the placeholders which are filled in automatically. The
+// argument is the text after the colon, e.g. VAR in ${env:VAR}.
+var builtins = map[string]func(arg string) (string, error){
+	"date": func(string) (string, error) {
+		return time.Now().Format("2006-01-02"), nil
+	},
+	"clipboard": func(string) (string, error) {
+		return clipboard.ReadAll()
+	},
+	"env": func(arg string) (string, error) {
+		return os.Getenv(arg), nil
+	},
+}
+
+// isTemplate returns whether the snippet is a template.
+func isTemplate(snippet store.Snippet) bool {
+	return snippet.HasTag(templateTag)
+}
+
+// placeholders returns the placeholders in content that need a value, in
+// order of appearance and without duplicates or built-ins.
+func placeholders(content string) []placeholder {
+	var result []placeholder
+	seen := map[string]bool{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := builtins[name]; ok {
+			continue
+		}
+		seen[name] = true
+		result = append(result, placeholder{Name: name, Default: match[2]})
+	}
+	return result
+}
+
+// renderTemplate replaces the placeholders in content with the given values,
+// falling back to their defaults. Values also take precedence over built-ins.
+func renderTemplate(content string, values map[string]string) (string, error) {
+	var err error
+	rendered := placeholderPattern.ReplaceAllStringFunc(content, func(s string) string {
+		if s == "$${" {
+			return "${"
+		}
+		match := placeholderPattern.FindStringSubmatch(s)
+		name, arg := match[1], match[2]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		if builtin, ok := builtins[name]; ok {
+			value, e := builtin(arg)
+			if e != nil && err == nil {
+				err = fmt.Errorf("could not fill in ${%s}: %w", name, e)
+			}
+			return value
+		}
+		if arg == "" && err == nil {
+			err = fmt.Errorf("missing value for ${%s}", name)
+		}
+		return arg
+	})
+	return rendered, err
+}
+
+// promptPlaceholders asks for the value of each placeholder which does not
+// have one yet, reading answers from r. An empty answer keeps the default.
+func promptPlaceholders(w io.Writer, r io.Reader, placeholders []placeholder, values map[string]string) error {
+	scanner := bufio.NewScanner(r)
+	for _, p := range placeholders {
+		if _, ok := values[p.Name]; ok {
+			continue
+		}
+		if p.Default != "" {
+			fmt.Fprintf(w, "%s (%s): ", p.Name, p.Default)
+		} else {
+			fmt.Fprintf(w, "%s: ", p.Name)
+		}
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			return io.ErrUnexpectedEOF
+		}
+		if answer := strings.TrimSpace(scanner.Text()); answer != "" {
+			values[p.Name] = answer
+		}
+	}
+	return nil
+}
+
+// varsFlag is a flag which can be repeated to collect name=value pairs.
+type varsFlag map[string]string
+
+// String returns the pairs joined by commas.
+func (v varsFlag) String() string {
+	var pairs []string
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds the name=value pair to the flag.
+func (v varsFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid variable %q, expected name=value", value)
+	}
+	v[name] = val
+	return nil
+}
+
+// selectedPlaceholders returns the placeholders to fill in if the selected
+// snippet is a template.
+func (m *Model) selectedPlaceholders() []placeholder {
+	snippet := m.selectedSnippet()
+	if !isTemplate(snippet) {
+		return nil
+	}
+	content, err := m.store.Content(snippet)
+	if err != nil {
+		return nil
+	}
+	return placeholders(string(content))
+}
+
+// copySnippet copies the selected snippet to the clipboard, filling in the
+// placeholders with values if it is a template, and returns a Cmd showing
+// that it was copied. Templates which cannot be filled in are not copied.
+func (m *Model) copySnippet(values map[string]string) tea.Cmd {
+	snippet := m.selectedSnippet()
+	b, err := m.store.Content(snippet)
+	if err != nil {
+		return changeState(navigatingState)
+	}
+	content := string(b)
+	if isTemplate(snippet) {
+		if content, err = renderTemplate(content, values); err != nil {
+			return copyError("Unable to fill in template: " + err.Error())
+		}
+	}
+	if err := clipboard.WriteAll(content); err != nil {
+		return copyError("Unable to copy snippet: " + err.Error())
+	}
+	return tea.Sequence(changeState(copyingState), m.useSnippet(snippet))
+}
+
+// copyError returns a Cmd going back to navigation and displaying why the
+// snippet was not copied.
+func copyError(msg string) tea.Cmd {
+	return tea.Sequence(changeState(navigatingState), func() tea.Msg {
+		return errorMsg(msg)
+	})
+}
+
+// useSnippet returns a Cmd recording that the snippet was used.
+func (m *Model) useSnippet(snippet store.Snippet) tea.Cmd {
+	return func() tea.Msg {
+		used, err := m.store.Used(snippet)
+		if err != nil {
+			return nil
+		}
+		return snippetEditedMsg(used)
+	}
+}
+
+// fillTemplate shows a form in the content pane to fill in the placeholders
+// of the selected template before copying it.
+func (m *Model) fillTemplate(placeholders []placeholder) tea.Cmd {
+	m.placeholders = placeholders
+	m.templateInputs = make([]textinput.Model, len(placeholders))
+	for i, p := range placeholders {
+		m.templateInputs[i] = newTextInput(p.Default)
+	}
+	m.templateInput = 0
+	m.state = fillingTemplateState
+	m.pane = contentPane
+	m.updateKeyMap()
+	m.updateActivePane(nil)
+	return m.templateInputs[0].Focus()
+}
+
+// updateTemplateForm handles the key presses while filling in a template.
+func (m *Model) updateTemplateForm(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.pane = snippetPane
+		return changeState(navigatingState)
+	case "tab", "down", "shift+tab", "up", "enter":
+		if msg.String() == "enter" && m.templateInput == len(m.templateInputs)-1 {
+			values := map[string]string{}
+			for i, input := range m.templateInputs {
+				if input.Value() != "" {
+					values[m.placeholders[i].Name] = input.Value()
+				}
+			}
+			m.pane = snippetPane
+			return m.copySnippet(values)
+		}
+		m.templateInputs[m.templateInput].Blur()
+		if msg.String() == "shift+tab" || msg.String() == "up" {
+			m.templateInput = (m.templateInput - 1 + len(m.templateInputs)) % len(m.templateInputs)
+		} else {
+			m.templateInput = (m.templateInput + 1) % len(m.templateInputs)
+		}
+		return m.templateInputs[m.templateInput].Focus()
+	}
+
+	var cmd tea.Cmd
+	m.templateInputs[m.templateInput], cmd = m.templateInputs[m.templateInput].Update(msg)
+	return cmd
+}
+
+// templateFormView renders the form to fill in the placeholders.
+func (m *Model) templateFormView() string {
+	var width int
+	for _, p := range m.placeholders {
+		if len(p.Name) > width {
+			width = len(p.Name)
+		}
+	}
+
+	var s strings.Builder
+	s.WriteString(m.ContentStyle.EmptyHint.Render("Fill in the template to copy it.") + "\n\n")
+	for i, p := range m.placeholders {
+		name := m.ContentStyle.EmptyHint.Render(fmt.Sprintf("%-*s", width, p.Name))
+		if i == m.templateInput {
+			name = m.ContentStyle.EmptyHintKey.Render(fmt.Sprintf("%-*s", width, p.Name))
+		}
+		s.WriteString(name + " " + m.templateInputs[i].View() + "\n")
+	}
+	s.WriteString("\n" + m.ContentStyle.EmptyHint.Render("enter • copy   tab • next   esc • cancel"))
+	return s.String()
+}
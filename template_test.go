@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maaslalani/nap/store"
+)
+
+func TestPlaceholders(t *testing.T) {
+	got := fmt.Sprint(placeholders("${host:localhost}:${port} ${date} $${raw} ${host}"))
+	if want := "[{host localhost} {port }]"; got != want {
+		t.Logf("placeholders are incorrect: want %s but got %s", want, got)
+		t.FailNow()
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	os.Setenv("NAP_TEST_USER", "nap")
+
+	tt := []struct {
+		Name    string
+		Content string
+		Values  map[string]string
+		Want    string
+		Err     bool
+	}{
+		{Name: "values", Content: "${host}:${port}", Values: map[string]string{"host": "localhost", "port": "80"}, Want: "localhost:80"},
+		{Name: "defaults", Content: "${host:localhost}:${port:80}", Want: "localhost:80"},
+		{Name: "env", Content: "${env:NAP_TEST_USER}", Want: "nap"},
+		{Name: "escaped", Content: "$${host}", Want: "${host}"},
+		{Name: "missing", Content: "${host}", Err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := renderTemplate(tc.Content, tc.Values)
+			if tc.Err != (err != nil) {
+				t.Logf("error is incorrect: want error %t but got %v", tc.Err, err)
+				t.FailNow()
+			}
+			if !tc.Err && got != tc.Want {
+				t.Logf("rendered template is incorrect: want %q but got %q", tc.Want, got)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestPromptPlaceholders(t *testing.T) {
+	values := map[string]string{"host": "example.com"}
+	p := placeholders("${host}:${port:80} ${path}")
+	var prompts strings.Builder
+	if err := promptPlaceholders(&prompts, strings.NewReader("\napi\n"), p, values); err != nil {
+		t.Logf("could not prompt placeholders: %v", err)
+		t.FailNow()
+	}
+	if want := "port (80): path: "; prompts.String() != want {
+		t.Logf("prompts are incorrect: want %q but got %q", want, prompts.String())
+		t.FailNow()
+	}
+	got, _ := renderTemplate("${host}:${port:80}/${path}", values)
+	if want := "example.com:80/api"; got != want {
+		t.Logf("rendered template is incorrect: want %q but got %q", want, got)
+		t.FailNow()
+	}
+}
+
+func TestTemplateCLI(t *testing.T) {
+	tmp := tmpHome(t)
+	st := store.New(tmp, "snippets.json")
+	snippet := store.Snippet{Tags: []string{templateTag}, Folder: "foo", Name: "url", File: "url.txt", Language: "txt"}
+	if err := st.Create(snippet, []byte("http://${host}:${port:80}")); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "plain.txt"), []byte("${host}"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "vars", Args: []string{"--var", "host=localhost", "foo/url"}, Want: "http://localhost:80"},
+		{Name: "raw", Args: []string{"--raw", "foo/url"}, Want: "http://${host}:${port:80}"},
+		{Name: "not a template", Args: []string{"foo/plain"}, Want: "${host}"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Logf("could not open pipe: %v", err)
+				t.FailNow()
+			}
+			os.Stdout = w
+			runCLI(tc.Args)
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Log("could not read stdout")
+				t.FailNow()
+			}
+
+			if string(out) != tc.Want {
+				t.Logf("output is incorrect: want %q but got %q", tc.Want, string(out))
+				t.FailNow()
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maaslalani/nap/store"
+	"github.com/sahilm/fuzzy"
+)
+
+// trashGlyph marks the view of the deleted snippets.
+const trashGlyph = "⌫"
+
+// trashItem is a deleted snippet listed in the trash view.
+type trashItem struct{ store.Trashed }
+
+// FilterValue is the searchable value of the deleted snippet.
+func (t trashItem) FilterValue() string {
+	return t.Snippet.FilterValue()
+}
+
+// trashView returns the view of the deleted snippets.
+func (m *Model) trashView() view {
+	return view{
+		name: trashGlyph + " Trash",
+		items: func() []list.Item {
+			items := make([]list.Item, len(m.trash))
+			for i, trashed := range m.trash {
+				items[i] = trashItem{trashed}
+			}
+			return items
+		},
+	}
+}
+
+// inTrash returns whether the trash view is selected.
+func (m *Model) inTrash() bool {
+	v, ok := m.Folders.SelectedItem().(view)
+	return ok && v.items != nil
+}
+
+// trashSnippet moves the selected snippet to the trash, where it can be
+// restored from, or purges it if it is already in the trash.
+func (m *Model) trashSnippet() {
+	if t, ok := m.List().SelectedItem().(trashItem); ok {
+		_ = m.store.Purge(t.Trashed)
+		m.removeTrashed(t.ID)
+		m.List().RemoveItem(m.List().Index())
+		return
+	}
+
+	trashed, err := m.store.Trash(m.selectedSnippet())
+	if err != nil {
+		return
+	}
+	m.removeSelectedSnippet()
+	m.trash = append([]store.Trashed{trashed}, m.trash...)
+	m.lastDeleted = &trashed
+}
+
+// restoreTrashed moves the deleted snippet out of the trash and selects it.
+func (m *Model) restoreTrashed(trashed store.Trashed) tea.Cmd {
+	snippet, err := m.store.Untrash(trashed)
+	if errors.Is(err, store.ErrExists) {
+		m.displayError("A snippet already exists at " + snippet.String() + ".")
+		return nil
+	}
+	if err != nil {
+		m.displayError("Unable to restore snippet.")
+		return nil
+	}
+	m.removeTrashed(trashed.ID)
+
+	folder := Folder(snippet.Folder)
+	if _, ok := m.Lists[folder]; !ok {
+		m.Lists[folder] = newList([]list.Item{}, m.height, m.ListStyle)
+	}
+	cmd := m.Lists[folder].InsertItem(0, snippet)
+	m.Folders.SetItems(m.folderItems())
+	m.selectSnippet(snippet)
+	return tea.Batch(cmd, m.updateContent())
+}
+
+// removeTrashed removes the deleted snippet from the trash view.
+func (m *Model) removeTrashed(id string) {
+	for i, trashed := range m.trash {
+		if trashed.ID == id {
+			m.trash = append(m.trash[:i], m.trash[i+1:]...)
+			break
+		}
+	}
+	if m.lastDeleted != nil && m.lastDeleted.ID == id {
+		m.lastDeleted = nil
+	}
+}
+
+// runTrash runs the trash subcommand to list, restore or empty the deleted
+// snippets.
+func runTrash(w io.Writer, st *store.Store, args []string) error {
+	trash, err := st.ListTrash()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		for _, trashed := range trash {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", trashed.ID, trashed.Snippet, trashed.Deleted.Format("2006-01-02 15:04:05"))
+		}
+	case "restore":
+		if len(args) < 2 {
+			return errUsage("trash restore <snippet>")
+		}
+		trashed, ok := findTrashed(args[1], trash)
+		if !ok {
+			return fmt.Errorf("%q is not in the trash", args[1])
+		}
+		snippet, err := st.Untrash(trashed)
+		if err != nil {
+			return fmt.Errorf("could not restore %s: %w", snippet, err)
+		}
+		fmt.Fprintf(w, "Restored %s\n", snippet)
+	case "empty":
+		n, err := st.EmptyTrash(time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Purged %d snippet(s)\n", n)
+	default:
+		return usageError(fmt.Sprintf("unknown trash command %q", args[0]))
+	}
+	return nil
+}
+
+// findTrashed returns the deleted snippet with the given trash or snippet ID
+// or the most recently deleted one fuzzy matching the search.
+func findTrashed(search string, trash []store.Trashed) (store.Trashed, bool) {
+	snippets := make([]store.Snippet, len(trash))
+	for i, trashed := range trash {
+		if trashed.ID == search || trashed.Snippet.ID == search {
+			return trashed, true
+		}
+		snippets[i] = trashed.Snippet
+	}
+	matches := fuzzy.FindFrom(search, Snippets{snippets})
+	if len(matches) == 0 {
+		return store.Trashed{}, false
+	}
+	best := matches[0]
+	for _, match := range matches {
+		if match.Score == best.Score && match.Index < best.Index {
+			best = match
+		}
+	}
+	return trash[best.Index], true
+}
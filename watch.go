@@ -0,0 +1,138 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maaslalani/nap/store"
+)
+
+const (
+	// watchInterval is how often home is scanned for changes where it cannot
+	// be watched natively.
+	watchInterval = time.Second
+	// settleDelay is how long to wait for more changes after one is reported,
+	// so that a git pull or a script writing many files reloads only once.
+	settleDelay = 200 * time.Millisecond
+	// retryDelay is how long to wait before reloading again while the user is
+	// busy, for example typing a name.
+	retryDelay = 500 * time.Millisecond
+)
+
+// storeChangedMsg tells the application that files changed in home.
+type storeChangedMsg struct{}
+
+// reloadMsg tells the application to reload the snippets if they changed.
+type reloadMsg struct{}
+
+// waitForChanges returns a Cmd waiting for the next change in home, if it is
+// watched.
+func (m *Model) waitForChanges() tea.Cmd {
+	if m.watcher == nil {
+		return nil
+	}
+	changes := m.watcher.Changes()
+	return func() tea.Msg {
+		<-changes
+		time.Sleep(settleDelay)
+		select {
+		case <-changes:
+		default:
+		}
+		return storeChangedMsg{}
+	}
+}
+
+// reloadWhenIdle reloads the snippets if they changed, once the user is back
+// to navigating so that no edit in progress is disrupted.
+func (m *Model) reloadWhenIdle() tea.Cmd {
+	if !m.stale {
+		return nil
+	}
+	if m.state != navigatingState {
+		return tea.Tick(retryDelay, func(time.Time) tea.Msg {
+			return reloadMsg{}
+		})
+	}
+	m.stale = false
+	return m.reload()
+}
+
+// reload merges the snippets changed on disk since they were last loaded,
+// by nap or other programs, into the lists. Changes made in the meantime in
+// the lists are kept, see store.Merge. The selected folder and snippet stay
+// selected if they still exist, and the content is refreshed.
+func (m *Model) reload() tea.Cmd {
+	theirs, err := m.store.Load()
+	if err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	merged := store.Merge(m.base, m.allSnippets(), theirs)
+	m.base = theirs
+	m.trash, _ = m.store.ListTrash()
+
+	selectedFolder := m.Folders.SelectedItem()
+	selected := m.List().SelectedItem()
+
+	folders := make(map[Folder][]list.Item)
+	for _, snippet := range merged {
+		folders[Folder(snippet.Folder)] = append(folders[Folder(snippet.Folder)], snippet)
+	}
+	diskFolders, _ := m.store.Folders()
+	for _, folder := range diskFolders {
+		if _, ok := folders[Folder(folder)]; !ok {
+			folders[Folder(folder)] = []list.Item{}
+		}
+	}
+	var cmds []tea.Cmd
+	for folder := range m.Lists {
+		if _, ok := folders[folder]; !ok {
+			delete(m.Lists, folder)
+		}
+	}
+	for folder, items := range folders {
+		if order := m.orders[string(folder)]; !order.manual() {
+			sortItems(items, order, m.positions)
+		}
+		li, ok := m.Lists[folder]
+		if !ok {
+			m.Lists[folder] = newList(items, m.height, m.ListStyle)
+			continue
+		}
+		cmds = append(cmds, li.SetItems(items))
+	}
+
+	folderItems := m.folderItems()
+	cmds = append(cmds, m.Folders.SetItems(folderItems))
+	if selectedFolder != nil {
+		for i, item := range folderItems {
+			if item.FilterValue() == selectedFolder.FilterValue() {
+				m.Folders.Select(i)
+				break
+			}
+		}
+	}
+	m.refreshView()
+	for i, item := range m.List().Items() {
+		if sameItem(item, selected) {
+			m.List().Select(i)
+			break
+		}
+	}
+	return tea.Batch(append(cmds, m.updateContent())...)
+}
+
+// allSnippets returns the snippets of every folder, in their manual order.
+func (m *Model) allSnippets() []store.Snippet {
+	var snippets []store.Snippet
+	for folder, li := range m.Lists {
+		for _, item := range m.manualItems(folder, li.Items()) {
+			if snippet, ok := item.(store.Snippet); ok {
+				snippets = append(snippets, snippet)
+			}
+		}
+	}
+	return snippets
+}